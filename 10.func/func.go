@@ -14,6 +14,17 @@ func main() {
 	}
 
 	paramsAndEffects()
+
+	// compute(fn) calls fn once; numerical methods call it until they
+	// have an answer.
+	numericMain()
+	// 1.414213562372 41
+	// 1.414213562373 6
+	// 1.414213562373 8
+	// 2.718281828459
+	// 3.141592653590
+	// 2.000000
+	// Bisect: root not bracketed: f(a) and f(b) have the same sign
 }
 
 func functionValues() {
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// compute takes a function and calls it. Numerical methods do the same with
+// more purpose: given f as a func(float64) float64 they find where it is zero,
+// how steep it is, the area under it, or where it is smallest, calling f as
+// often as they need to. Each method returns a Result with an error estimate
+// and how much work it took, and an error if it could not meet the tolerance.
+
+// Result is the answer of a numerical method.
+type Result struct {
+	X    float64 // the root, derivative, integral or minimum point
+	Err  float64 // estimated absolute error of X
+	Iter int     // iterations; subintervals for Simpson and Integrate
+}
+
+var (
+	// ErrNoBracket means f(a) and f(b) have the same sign, so [a, b] need not
+	// contain a root.
+	ErrNoBracket = errors.New("root not bracketed: f(a) and f(b) have the same sign")
+	// ErrNoConvergence means the tolerance was not met within the iteration
+	// limit. The Result returned with it holds the best estimate so far.
+	ErrNoConvergence = errors.New("did not converge")
+	// ErrZeroDerivative means Newton's method hit a flat spot of f.
+	ErrZeroDerivative = errors.New("zero derivative")
+	// ErrBadSubintervals means Simpson was given a number of subintervals
+	// that is odd or less than 4.
+	ErrBadSubintervals = errors.New("number of subintervals must be even and at least 4")
+)
+
+// maxIter bounds the iterations of every method.
+const maxIter = 200
+
+func notConverged(method string, r Result) (Result, error) {
+	return r, fmt.Errorf("%s: %w after %d iterations (error %g)", method, ErrNoConvergence, r.Iter, r.Err)
+}
+
+// Bisect finds a root of f in [a, b] by halving the interval until it is
+// shorter than 2*tol. f(a) and f(b) must have opposite signs. It gains one
+// bit per iteration, slowly but surely.
+func Bisect(f func(float64) float64, a, b, tol float64) (Result, error) {
+	fa, fb := f(a), f(b)
+	switch {
+	case fa == 0:
+		return Result{X: a}, nil
+	case fb == 0:
+		return Result{X: b}, nil
+	case math.Signbit(fa) == math.Signbit(fb):
+		return Result{}, fmt.Errorf("Bisect: %w", ErrNoBracket)
+	}
+	var r Result
+	for r.Iter = 1; r.Iter <= maxIter; r.Iter++ {
+		m := a + (b-a)/2
+		r.X, r.Err = m, math.Abs(b-a)/2
+		if r.Err <= tol {
+			return r, nil
+		}
+		fm := f(m)
+		if fm == 0 {
+			r.Err = 0
+			return r, nil
+		}
+		if math.Signbit(fm) == math.Signbit(fa) {
+			a, fa = m, fm
+		} else {
+			b = m
+		}
+	}
+	r.Iter = maxIter
+	return notConverged("Bisect", r)
+}
+
+// Newton finds a root of f starting from x0, following the tangent line:
+// x ← x - f(x)/f'(x). df is f'; if it is nil, Derivative estimates it. Near a
+// simple root the number of correct digits doubles every step, but a poor x0
+// can send it anywhere.
+func Newton(f, df func(float64) float64, x0, tol float64) (Result, error) {
+	if df == nil {
+		df = func(x float64) float64 {
+			d, _ := Derivative(f, x)
+			return d.X
+		}
+	}
+	r := Result{X: x0, Err: math.Inf(1)}
+	for r.Iter = 1; r.Iter <= maxIter; r.Iter++ {
+		fx := f(r.X)
+		if fx == 0 {
+			r.Err = 0
+			return r, nil
+		}
+		d := df(r.X)
+		if d == 0 {
+			return r, fmt.Errorf("Newton: %w at x = %g", ErrZeroDerivative, r.X)
+		}
+		step := fx / d
+		r.X -= step
+		r.Err = math.Abs(step)
+		if math.IsNaN(r.X) || math.IsInf(r.X, 0) {
+			return notConverged("Newton", r)
+		}
+		if r.Err <= tol {
+			return r, nil
+		}
+	}
+	r.Iter = maxIter
+	return notConverged("Newton", r)
+}
+
+// Brent finds a root of f in [a, b], where f(a) and f(b) have opposite signs.
+// It tries inverse quadratic interpolation and the secant method, which are
+// fast, and falls back to bisection whenever they do not shrink the bracket
+// enough, so it is never much slower than Bisect and usually far faster.
+func Brent(f func(float64) float64, a, b, tol float64) (Result, error) {
+	fa, fb := f(a), f(b)
+	if fa != 0 && fb != 0 && math.Signbit(fa) == math.Signbit(fb) {
+		return Result{}, fmt.Errorf("Brent: %w", ErrNoBracket)
+	}
+	// b is the best estimate, a the previous one, and the root lies between
+	// b and c.
+	c, fc := b, fb
+	var d, e float64
+	var r Result
+	for r.Iter = 1; r.Iter <= maxIter; r.Iter++ {
+		if fb != 0 && fc != 0 && math.Signbit(fb) == math.Signbit(fc) {
+			c, fc = a, fa
+			d = b - a
+			e = d
+		}
+		if math.Abs(fc) < math.Abs(fb) {
+			a, b, c = b, c, b
+			fa, fb, fc = fb, fc, fb
+		}
+		tol1 := 2*epsilon*math.Abs(b) + tol/2
+		xm := (c - b) / 2
+		r.X, r.Err = b, math.Abs(xm)
+		if math.Abs(xm) <= tol1 || fb == 0 {
+			if fb == 0 {
+				r.Err = 0
+			}
+			return r, nil
+		}
+		if math.Abs(e) >= tol1 && math.Abs(fa) > math.Abs(fb) {
+			var p, q float64
+			s := fb / fa
+			if a == c {
+				// Secant.
+				p = 2 * xm * s
+				q = 1 - s
+			} else {
+				// Inverse quadratic interpolation.
+				q = fa / fc
+				t := fb / fc
+				p = s * (2*xm*q*(q-t) - (b-a)*(t-1))
+				q = (q - 1) * (t - 1) * (s - 1)
+			}
+			if p > 0 {
+				q = -q
+			}
+			p = math.Abs(p)
+			if 2*p < min(3*xm*q-math.Abs(tol1*q), math.Abs(e*q)) {
+				e, d = d, p/q
+			} else {
+				d = xm // interpolation failed: bisect
+				e = d
+			}
+		} else {
+			d = xm
+			e = d
+		}
+		a, fa = b, fb
+		if math.Abs(d) > tol1 {
+			b += d
+		} else {
+			b += math.Copysign(tol1, xm)
+		}
+		fb = f(b)
+	}
+	r.Iter = maxIter
+	return notConverged("Brent", r)
+}
+
+// epsilon is the gap between 1 and the next float64.
+const epsilon = 0x1p-52
+
+// Derivative estimates f'(x) with central differences (f(x+h) - f(x-h)) / 2h
+// for shrinking h, extrapolated to h = 0 (Ridders' method). Smaller h alone
+// would lose to rounding error; extrapolation reaches about 10 digits.
+func Derivative(f func(float64) float64, x float64) (Result, error) {
+	const (
+		shrink = 1.4
+		steps  = 10
+	)
+	h := 0.1 * max(math.Abs(x), 1)
+	// t[j][i] is the i-th difference quotient extrapolated j times.
+	var t [steps][steps]float64
+	t[0][0] = (f(x+h) - f(x-h)) / (2 * h)
+	r := Result{X: t[0][0], Err: math.Inf(1)}
+	for i := 1; i < steps; i++ {
+		r.Iter = i
+		h /= shrink
+		t[0][i] = (f(x+h) - f(x-h)) / (2 * h)
+		fac := shrink * shrink
+		for j := 1; j <= i; j++ {
+			// The error of a central difference goes as h², so combining two
+			// estimates cancels the leading term.
+			t[j][i] = (t[j-1][i]*fac - t[j-1][i-1]) / (fac - 1)
+			fac *= shrink * shrink
+			err := max(math.Abs(t[j][i]-t[j-1][i]), math.Abs(t[j][i]-t[j-1][i-1]))
+			if err <= r.Err {
+				r.X, r.Err = t[j][i], err
+			}
+		}
+		// Stop once rounding error makes the higher orders worse.
+		if math.Abs(t[i][i]-t[i-1][i-1]) >= 2*r.Err {
+			break
+		}
+	}
+	if math.IsNaN(r.X) {
+		return notConverged("Derivative", r)
+	}
+	return r, nil
+}
+
+// Simpson integrates f over [a, b] with Simpson's rule on n subintervals,
+// n even: parabolas through each pair of subintervals. When n is a multiple
+// of 4, the error is estimated by comparing with the rule on n/2
+// subintervals; otherwise Err is NaN.
+func Simpson(f func(float64) float64, a, b float64, n int) (Result, error) {
+	if n < 4 || n%2 != 0 {
+		return Result{}, fmt.Errorf("Simpson: %w, not %d", ErrBadSubintervals, n)
+	}
+	h := (b - a) / float64(n)
+	// fine is Simpson on n subintervals, coarse on n/2, from the same points.
+	ends := f(a) + f(b)
+	var odd, evenOnly, quarter float64
+	for i := 1; i < n; i++ {
+		y := f(a + float64(i)*h)
+		switch {
+		case i%2 == 1:
+			odd += y
+		case i%4 == 2:
+			evenOnly += y // odd points of the coarse rule
+		default:
+			quarter += y // even points of the coarse rule
+		}
+	}
+	fine := h / 3 * (ends + 4*odd + 2*(evenOnly+quarter))
+	r := Result{X: fine, Iter: n}
+	if n%4 == 0 {
+		coarse := 2 * h / 3 * (ends + 4*evenOnly + 2*quarter)
+		r.Err = math.Abs(fine-coarse) / 15
+	} else {
+		r.Err = math.NaN() // no coarser Simpson rule on these points
+	}
+	return r, nil
+}
+
+// Gauss-Kronrod 7-15 nodes and weights on [-1, 1], from QUADPACK. xgk[1],
+// xgk[3] and xgk[5] are also the Gauss nodes, with weights wg.
+var (
+	xgk = [8]float64{
+		0.991455371120812639206854697526329, 0.949107912342758524526189684047851,
+		0.864864423359769072789712788640926, 0.741531185599394439863864773280788,
+		0.586087235467691130294144845693013, 0.405845151377397166906606412076961,
+		0.207784955007898467600689403773245, 0,
+	}
+	wgk = [8]float64{
+		0.022935322010529224963732008058970, 0.063092092629978553290700663189204,
+		0.104790010322250183839876322541518, 0.140653259715525918745189590510238,
+		0.169004726639267902826583426598550, 0.190350578064785409913256402421014,
+		0.204432940075298892414161999234649, 0.209482141084727828012999174891714,
+	}
+	wg = [4]float64{
+		0.129484966168869693270611432679082, 0.279705391489276667901467771423780,
+		0.381830050505118944950369775488975, 0.417959183673469387755102040816327,
+	}
+)
+
+// gk15 integrates f over [a, b] with the 15-point Kronrod rule, and returns
+// its difference from the embedded 7-point Gauss rule as the error.
+func gk15(f func(float64) float64, a, b float64) (sum, err float64) {
+	center, half := (a+b)/2, (b-a)/2
+	fc := f(center)
+	kronrod := wgk[7] * fc
+	gauss := wg[3] * fc
+	for i := 0; i < 7; i++ {
+		dx := half * xgk[i]
+		y := f(center-dx) + f(center+dx)
+		kronrod += wgk[i] * y
+		if i%2 == 1 {
+			gauss += wg[i/2] * y
+		}
+	}
+	return kronrod * half, math.Abs((kronrod - gauss) * half)
+}
+
+// Integrate integrates f over [a, b] to within tol. It applies the
+// Gauss-Kronrod 7-15 rule and keeps splitting the subinterval with the
+// largest error in two, so the points gather where f is hard to integrate,
+// such as near a kink or an integrable singularity at an end. A sum or
+// error that is NaN or infinite, as from a pole inside [a, b] or an f that
+// returns NaN, is not accepted as an answer.
+func Integrate(f func(float64) float64, a, b, tol float64) (Result, error) {
+	type piece struct{ a, b, sum, err float64 }
+	sum, err := gk15(f, a, b)
+	pieces := []piece{{a, b, sum, err}}
+	r := Result{X: sum, Err: err, Iter: 1}
+	// Written so that a NaN error keeps the loop going.
+	for !(r.Err <= tol) {
+		if r.Iter >= maxIter {
+			return notConverged("Integrate", r)
+		}
+		worst := 0
+		for i, p := range pieces {
+			if math.IsNaN(p.err) || p.err > pieces[worst].err {
+				worst = i
+			}
+		}
+		p := pieces[worst]
+		m := (p.a + p.b) / 2
+		ls, le := gk15(f, p.a, m)
+		rs, re := gk15(f, m, p.b)
+		pieces[worst] = piece{p.a, m, ls, le}
+		pieces = append(pieces, piece{m, p.b, rs, re})
+		r.Iter++
+		// Add the pieces up afresh rather than adjusting running totals,
+		// which would collect rounding error.
+		r.X, r.Err = 0, 0
+		for _, p := range pieces {
+			r.X += p.sum
+			r.Err += p.err
+		}
+	}
+	if math.IsNaN(r.X) || math.IsInf(r.X, 0) || math.IsInf(r.Err, 0) {
+		return notConverged("Integrate", r)
+	}
+	return r, nil
+}
+
+// GoldenSection finds a minimum of f in [a, b], which should hold only one,
+// to within tol. Each step keeps the part of the bracket that must hold the
+// minimum and shrinks it by the golden ratio, reusing one of the two inner
+// points, so it costs one call of f per step. f is flat near a minimum, so
+// values of f cannot tell points apart that are closer than about √ε·|x|,
+// 1.5e-8·|x|: a smaller tol gives no more accuracy.
+func GoldenSection(f func(float64) float64, a, b, tol float64) (Result, error) {
+	const invPhi = 0.6180339887498949 // 1/φ = φ - 1
+	x1, x2 := b-invPhi*(b-a), a+invPhi*(b-a)
+	f1, f2 := f(x1), f(x2)
+	var r Result
+	for r.Iter = 1; r.Iter <= maxIter; r.Iter++ {
+		r.X, r.Err = (a+b)/2, math.Abs(b-a)/2
+		if r.Err <= tol {
+			return r, nil
+		}
+		if f1 < f2 {
+			b, x2, f2 = x2, x1, f1
+			x1 = b - invPhi*(b-a)
+			f1 = f(x1)
+		} else {
+			a, x1, f1 = x1, x2, f2
+			x2 = a + invPhi*(b-a)
+			f2 = f(x2)
+		}
+	}
+	r.Iter = maxIter
+	return notConverged("GoldenSection", r)
+}
+
+func numericMain() {
+	// √2 is the root of x² - 2. Bisection gains one bit per step; Newton and
+	// Brent get there in a handful.
+	sq2 := func(x float64) float64 { return x*x - 2 }
+	r, _ := Bisect(sq2, 0, 2, 1e-12)
+	fmt.Printf("%.12f %d\n", r.X, r.Iter) // 1.414213562372 41
+	r, _ = Newton(sq2, func(x float64) float64 { return 2 * x }, 1, 1e-12)
+	fmt.Printf("%.12f %d\n", r.X, r.Iter) // 1.414213562373 6
+	r, _ = Brent(sq2, 0, 2, 1e-12)
+	fmt.Printf("%.12f %d\n", r.X, r.Iter) // 1.414213562373 8
+
+	r, _ = Derivative(math.Exp, 1)
+	fmt.Printf("%.12f\n", r.X) // 2.718281828459
+	r, _ = Integrate(func(x float64) float64 { return 4 / (1 + x*x) }, 0, 1, 1e-12)
+	fmt.Printf("%.12f\n", r.X) // 3.141592653590
+	r, _ = GoldenSection(func(x float64) float64 { return (x-2)*(x-2) + 1 }, 0, 5, 1e-8)
+	fmt.Printf("%.6f\n", r.X) // 2.000000
+
+	_, err := Bisect(sq2, 2, 3, 1e-12)
+	fmt.Println(err) // Bisect: root not bracketed: f(a) and f(b) have the same sign
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func sq2(x float64) float64 { return x*x - 2 }
+
+// Each case compares a method with a known closed form.
+func TestNumericMethods(t *testing.T) {
+	tests := []struct {
+		name   string
+		run    func() (Result, error)
+		want   float64
+		within float64
+	}{
+		{"Bisect √2", func() (Result, error) { return Bisect(sq2, 0, 2, 1e-12) }, math.Sqrt2, 1e-9},
+		{"Bisect root at a", func() (Result, error) {
+			return Bisect(func(x float64) float64 { return x - 1 }, 1, 3, 1e-12)
+		}, 1, 0},
+		{"Newton √2", func() (Result, error) {
+			return Newton(sq2, func(x float64) float64 { return 2 * x }, 1, 1e-12)
+		}, math.Sqrt2, 1e-9},
+		{"Newton π/2, estimated f'", func() (Result, error) { return Newton(math.Cos, nil, 1, 1e-12) }, math.Pi / 2, 1e-9},
+		{"Brent cos x = x", func() (Result, error) {
+			return Brent(func(x float64) float64 { return math.Cos(x) - x }, 0, 1, 1e-12)
+		}, 0.7390851332151607, 1e-9},
+		{"Brent √2", func() (Result, error) { return Brent(sq2, 0, 2, 1e-12) }, math.Sqrt2, 1e-9},
+		{"d/dx eˣ at 1", func() (Result, error) { return Derivative(math.Exp, 1) }, math.E, 1e-9},
+		{"d/dx sin at 0", func() (Result, error) { return Derivative(math.Sin, 0) }, 1, 1e-9},
+		{"Simpson sin", func() (Result, error) { return Simpson(math.Sin, 0, math.Pi, 1000) }, 2, 1e-9},
+		{"∫ 4/(1+x²)", func() (Result, error) {
+			return Integrate(func(x float64) float64 { return 4 / (1 + x*x) }, 0, 1, 1e-12)
+		}, math.Pi, 1e-9},
+		{"∫ √x", func() (Result, error) { return Integrate(math.Sqrt, 0, 1, 1e-10) }, 2.0 / 3, 1e-9},
+		{"min (x-2)²+1", func() (Result, error) {
+			return GoldenSection(func(x float64) float64 { return (x-2)*(x-2) + 1 }, 0, 5, 1e-8)
+		}, 2, 1e-7},
+	}
+	for _, tt := range tests {
+		r, err := tt.run()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if math.Abs(r.X-tt.want) > tt.within {
+			t.Errorf("%s = %.15g, want %.15g within %g", tt.name, r.X, tt.want, tt.within)
+		}
+		// The error estimate must not claim more accuracy than was reached.
+		if math.Abs(r.X-tt.want) > 10*r.Err+1e-15 {
+			t.Errorf("%s: actual error %g, estimated %g", tt.name, math.Abs(r.X-tt.want), r.Err)
+		}
+	}
+}
+
+func TestSimpsonErrorEstimate(t *testing.T) {
+	// The estimate is of the right size, not a strict bound.
+	r, _ := Simpson(math.Exp, 0, 1, 8)
+	if err := math.Abs(r.X - (math.E - 1)); math.IsNaN(r.Err) || err > 2*r.Err || err < r.Err/2 {
+		t.Errorf("Simpson(exp, 8) = %v ± %v, actual error %v", r.X, r.Err, err)
+	}
+	if r, _ := Simpson(math.Exp, 0, 1, 6); !math.IsNaN(r.Err) {
+		t.Errorf("Simpson with n = 6 has Err %v, want NaN", r.Err)
+	}
+	for _, n := range []int{5, 2, 0, -4} {
+		if _, err := Simpson(math.Exp, 0, 1, n); !errors.Is(err, ErrBadSubintervals) {
+			t.Errorf("Simpson with n = %d: %v, want ErrBadSubintervals", n, err)
+		}
+	}
+}
+
+func TestNumericErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func() (Result, error)
+		want error
+	}{
+		{"Bisect without a sign change", func() (Result, error) { return Bisect(sq2, 2, 3, 1e-12) }, ErrNoBracket},
+		{"Brent without a sign change", func() (Result, error) { return Brent(sq2, -1, 1, 1e-12) }, ErrNoBracket},
+		{"Newton at a flat spot", func() (Result, error) {
+			return Newton(func(x float64) float64 { return x*x + 1 }, func(x float64) float64 { return 2 * x }, 0, 1e-12)
+		}, ErrZeroDerivative},
+		// x² + 1 has no real root: Newton wanders forever.
+		{"Newton without a root", func() (Result, error) {
+			return Newton(func(x float64) float64 { return x*x + 1 }, func(x float64) float64 { return 2 * x }, 0.5, 1e-12)
+		}, ErrNoConvergence},
+		// No interval of floats around √2 is shorter than 0.
+		{"Bisect with tol 0", func() (Result, error) { return Bisect(sq2, 0, 2, 0) }, ErrNoConvergence},
+		{"GoldenSection with tol 0", func() (Result, error) {
+			return GoldenSection(func(x float64) float64 { return (x - 2) * (x - 2) }, 0, 5, 0)
+		}, ErrNoConvergence},
+		// 1/x cannot be integrated from 0.
+		{"Integrate 1/x", func() (Result, error) {
+			return Integrate(func(x float64) float64 { return 1 / x }, 0, 1, 1e-6)
+		}, ErrNoConvergence},
+		// The middle node of the first rule is the pole: f(0) = +Inf.
+		{"Integrate 1/x across 0", func() (Result, error) {
+			return Integrate(func(x float64) float64 { return 1 / x }, -1, 1, 1e-6)
+		}, ErrNoConvergence},
+		{"Integrate NaN", func() (Result, error) {
+			return Integrate(func(float64) float64 { return math.NaN() }, 0, 1, 1e-6)
+		}, ErrNoConvergence},
+	}
+	for _, tt := range tests {
+		r, err := tt.run()
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: error %v, want %v", tt.name, err, tt.want)
+			continue
+		}
+		if tt.want == ErrNoConvergence && r.Iter != maxIter {
+			t.Errorf("%s: Iter = %d, want the limit %d", tt.name, r.Iter, maxIter)
+		}
+	}
+
+	// The best estimate so far comes back with ErrNoConvergence.
+	r, _ := Bisect(sq2, 0, 2, 0)
+	if math.Abs(r.X-math.Sqrt2) > 1e-15 {
+		t.Errorf("Bisect with tol 0 returned %v, want √2", r.X)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A deferred call belongs to the function that registered it and its result
+// is thrown away. Cleanup is an explicit stack of clean-up steps: it runs them
+// in Last In First Out order like defer, but collects their errors and can be
+// passed to another goroutine or returned to a caller that then owns it.
+
+// Cleanup is a stack of clean-up steps. The zero value is ready to use
+// and a Cleanup is safe to use concurrently.
+type Cleanup struct {
+	mu    sync.Mutex
+	steps []cleanupStep
+	done  bool
+}
+
+type cleanupStep struct {
+	name    string
+	fn      func(ctx context.Context) error
+	timeout time.Duration
+}
+
+// ErrCleanupDone is returned when a step is added after Run.
+var ErrCleanupDone = errors.New("cleanup: already run")
+
+// StepError records the failure of one named step.
+type StepError struct {
+	Name string
+	Err  error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("cleanup %s: %v", e.Name, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// Add pushes a step that cannot fail.
+func (c *Cleanup) Add(name string, fn func()) error {
+	return c.AddErr(name, func() error {
+		fn()
+		return nil
+	})
+}
+
+// AddErr pushes a step that may fail.
+func (c *Cleanup) AddErr(name string, fn func() error) error {
+	return c.AddTimeout(name, 0, func(context.Context) error {
+		return fn()
+	})
+}
+
+// AddTimeout pushes a step whose context is cancelled after timeout.
+// A timeout of zero means no limit. If the step has not returned when the
+// timeout expires, Run records context.DeadlineExceeded for it and moves
+// on to the next step. A goroutine cannot be stopped from outside, so the
+// step keeps running in its own goroutine until it returns: fn should give
+// up when ctx is done, or it leaks that goroutine and whatever it holds.
+func (c *Cleanup) AddTimeout(name string, timeout time.Duration, fn func(ctx context.Context) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.done {
+		return ErrCleanupDone
+	}
+	c.steps = append(c.steps, cleanupStep{name, fn, timeout})
+	return nil
+}
+
+// Len returns the number of steps waiting to run.
+func (c *Cleanup) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.steps)
+}
+
+// Release hands all pending steps over to a new Cleanup and empties c.
+// It is used to pass ownership to another goroutine, or to return the
+// resources to a caller once a constructor has succeeded:
+//
+//	var c Cleanup
+//	defer c.Run(ctx) // only cleans up if we fail below
+//	...
+//	return c.Release(), nil
+func (c *Cleanup) Release() *Cleanup {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	steps := c.steps
+	c.steps = nil
+	return &Cleanup{steps: steps}
+}
+
+// Run pops and runs every step, last added first, even if some of them fail
+// or panic. It returns the errors of the failing steps joined with
+// errors.Join, or nil. Steps cannot be added once Run has been called.
+func (c *Cleanup) Run(ctx context.Context) error {
+	c.mu.Lock()
+	steps := c.steps
+	c.steps = nil
+	c.done = true
+	c.mu.Unlock()
+
+	var errs []error
+	for i := len(steps) - 1; i >= 0; i-- {
+		if err := steps[i].run(ctx); err != nil {
+			errs = append(errs, &StepError{Name: steps[i].name, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s cleanupStep) run(ctx context.Context) error {
+	if s.timeout <= 0 {
+		return tryErr(func() error { return s.fn(ctx) })
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	result := make(chan error, 1)
+	go func() {
+		result <- tryErr(func() error { return s.fn(ctx) })
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// tryErr is Try for functions that return an error.
+func tryErr(fn func() error) (err error) {
+	if perr := Try(func() { err = fn() }); perr != nil {
+		return perr
+	}
+	return err
+}
+
+func cleanupMain() {
+	var c Cleanup
+	c.Add("close file", func() { fmt.Println("file closed") })
+	c.AddErr("flush cache", func() error { return errors.New("disk full") })
+	c.AddTimeout("stop server", 10*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done() // the server never stops in time
+		return ctx.Err()
+	})
+
+	// The caller now owns the steps; c is empty.
+	owner := c.Release()
+	fmt.Println(c.Len(), owner.Len()) // 0 3
+
+	done := make(chan error)
+	go func() { done <- owner.Run(context.Background()) }()
+	err := <-done
+	// file closed
+	fmt.Println(err)
+	// cleanup stop server: context deadline exceeded
+	// cleanup flush cache: disk full
+	fmt.Println(errors.Is(err, context.DeadlineExceeded)) // true
+}
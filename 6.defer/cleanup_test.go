@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCleanupOrder(t *testing.T) {
+	var c Cleanup
+	var ran []string
+	for _, name := range []string{"a", "b", "c"} {
+		c.Add(name, func() { ran = append(ran, name) })
+	}
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"c", "b", "a"}; !slices.Equal(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+	if err := c.Add("late", func() {}); err != ErrCleanupDone {
+		t.Errorf("Add after Run = %v, want ErrCleanupDone", err)
+	}
+	if err := c.Run(context.Background()); err != nil || c.Len() != 0 {
+		t.Errorf("second Run = %v with %d steps, want nil and 0", err, c.Len())
+	}
+}
+
+func TestCleanupErrors(t *testing.T) {
+	errDisk := errors.New("disk full")
+	errNet := errors.New("network down")
+	var c Cleanup
+	c.AddErr("flush", func() error { return errDisk })
+	c.Add("log", func() {})
+	c.AddErr("hang up", func() error { return errNet })
+
+	err := c.Run(context.Background())
+	if !errors.Is(err, errDisk) || !errors.Is(err, errNet) {
+		t.Fatalf("Run = %v, want both step errors", err)
+	}
+	// errors.Join keeps the order the steps ran in.
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("Run returned %T, want an errors.Join error", err)
+	}
+	var names []string
+	for _, e := range joined.Unwrap() {
+		var se *StepError
+		if !errors.As(e, &se) {
+			t.Fatalf("%v is not a *StepError", e)
+		}
+		names = append(names, se.Name)
+	}
+	if want := []string{"hang up", "flush"}; !slices.Equal(names, want) {
+		t.Errorf("failed steps %v, want %v", names, want)
+	}
+	if got, want := err.Error(), "cleanup hang up: network down\ncleanup flush: disk full"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestCleanupTimeout(t *testing.T) {
+	var c Cleanup
+	release := make(chan struct{})
+	defer close(release)
+	c.AddTimeout("ignores ctx", 10*time.Millisecond, func(context.Context) error {
+		<-release // returns only when the test ends
+		return nil
+	})
+	c.AddTimeout("watches ctx", 10*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	c.AddTimeout("quick", time.Second, func(context.Context) error { return nil })
+
+	start := time.Now()
+	err := c.Run(context.Background())
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Run took %v, want about 20ms", d)
+	}
+	var se *StepError
+	if !errors.As(err, &se) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run = %v, want DeadlineExceeded in a StepError", err)
+	}
+	if got := len(err.(interface{ Unwrap() []error }).Unwrap()); got != 2 {
+		t.Errorf("%d steps failed, want 2: %v", got, err)
+	}
+}
+
+func TestCleanupPanic(t *testing.T) {
+	var c Cleanup
+	ran := false
+	c.Add("first", func() { ran = true })
+	c.Add("boom", func() { panic("boom") })
+	err := c.Run(context.Background())
+	if !ran {
+		t.Error("the step before the panicking one did not run")
+	}
+	var se *StepError
+	var pe *PanicError
+	if !errors.As(err, &se) || se.Name != "boom" || !errors.As(err, &pe) || pe.Value != "boom" {
+		t.Errorf("Run = %v, want a StepError for boom wrapping a PanicError", err)
+	}
+
+	// A panic in a step with a timeout is recovered in its goroutine.
+	var d Cleanup
+	d.AddTimeout("boom", time.Second, func(context.Context) error { panic("boom") })
+	if err := d.Run(context.Background()); !errors.As(err, &pe) {
+		t.Errorf("Run = %v, want a PanicError", err)
+	}
+}
+
+func TestCleanupRelease(t *testing.T) {
+	var c Cleanup
+	var ran []string
+	c.Add("a", func() { ran = append(ran, "a") })
+	c.Add("b", func() { ran = append(ran, "b") })
+	owner := c.Release()
+	if c.Len() != 0 || owner.Len() != 2 {
+		t.Fatalf("after Release: %d and %d steps, want 0 and 2", c.Len(), owner.Len())
+	}
+	// c is still usable, and running it no longer touches the released steps.
+	c.Add("c", func() { ran = append(ran, "c") })
+	c.Run(context.Background())
+	done := make(chan error)
+	go func() { done <- owner.Run(context.Background()) }()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"c", "b", "a"}; !slices.Equal(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+}
@@ -9,6 +9,8 @@ func main() {
 	b() // Panic in b Recover in b
 	c() // Func c
 	d()
+	// Before Go 1.22 (run loopvar/loopvar.go to compare with newer versions,
+	// and defertrace/defertrace.go to see each defer registered and run):
 	// Func d
 	// closure i =  4
 	// closure i =  4
@@ -27,6 +29,17 @@ func main() {
 	// defer_closure i =  4
 	// defer i =  0
 	fmt.Println(e()) // 2
+	recoverMain()
+	// true
+	// Panic in Try
+	// true
+	// Recovered: Panic in Go
+	cleanupMain()
+	// 0 3
+	// file closed
+	// cleanup stop server: context deadline exceeded
+	// cleanup flush cache: disk full
+	// true
 }
 
 func a() {
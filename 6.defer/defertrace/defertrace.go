@@ -0,0 +1,673 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defertrace rewrites the functions of a lesson so that every defer statement
+// logs when it is registered, with the argument values evaluated at that
+// moment, and when the deferred call later runs. Panics, recovers and the
+// values of named results before and after the deferred calls are logged too.
+// When an instrumented function returns, its timeline is printed to stderr; a
+// panic that no deferred call recovered is then raised again.
+//
+// Usage:
+//
+//	go run 6.defer/defertrace/defertrace.go [-func d,e] 6.defer
+func main() {
+	funcs := flag.String("func", "", "comma-separated functions to trace (default: all with a defer)")
+	keep := flag.Bool("keep", false, "keep the rewritten sources and print their directory")
+	flag.Parse()
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	var only map[string]bool
+	if *funcs != "" {
+		only = make(map[string]bool)
+		for _, name := range strings.Split(*funcs, ",") {
+			only[strings.TrimSpace(name)] = true
+		}
+	}
+
+	if err := trace(dir, only, *keep); err != nil {
+		fmt.Fprintln(os.Stderr, "defertrace:", err)
+		os.Exit(1)
+	}
+}
+
+func trace(dir string, only map[string]bool, keep bool) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return err
+	}
+	var names []string
+	var srcs [][]byte
+	for _, f := range files {
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		names = append(names, f)
+		srcs = append(srcs, src)
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no Go files in %s", dir)
+	}
+	out, err := instrument(names, srcs, only)
+	if err != nil {
+		return fmt.Errorf("%s: %v", dir, err)
+	}
+
+	tmp, err := os.MkdirTemp("", "defertrace")
+	if err != nil {
+		return err
+	}
+	if keep {
+		fmt.Fprintln(os.Stderr, "defertrace: sources in", tmp)
+	} else {
+		defer os.RemoveAll(tmp)
+	}
+
+	args := []string{"run", "zz_defertrace.go"}
+	if err := os.WriteFile(filepath.Join(tmp, args[1]), []byte(runtimeSrc), 0o644); err != nil {
+		return err
+	}
+	for i, src := range out {
+		name := filepath.Base(names[i])
+		if err := os.WriteFile(filepath.Join(tmp, name), src, 0o644); err != nil {
+			return err
+		}
+		args = append(args, name)
+	}
+
+	cmd := exec.Command("go", args...)
+	cmd.Dir = tmp
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// instrument rewrites the functions to trace in the given sources of one
+// package and returns the new sources. Without only, every function with a
+// defer is traced.
+func instrument(names []string, srcs [][]byte, only map[string]bool) ([][]byte, error) {
+	fset := token.NewFileSet()
+	var parsed []*ast.File
+	for i, name := range names {
+		file, err := parser.ParseFile(fset, name, srcs[i], parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, file)
+	}
+
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+	}
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error:    func(error) {},
+	}
+	conf.Check("main", fset, parsed, info)
+
+	var out [][]byte
+	traced := 0
+	for i, file := range parsed {
+		rw := &rewriter{fset: fset, info: info, src: srcs[i]}
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			if only != nil && !only[fn.Name.Name] || only == nil && !hasDefer(fn.Body) {
+				continue
+			}
+			rw.function(fn)
+			traced++
+		}
+		out = append(out, rw.apply())
+	}
+	if traced == 0 {
+		return nil, fmt.Errorf("no functions to trace")
+	}
+	return out, nil
+}
+
+// hasDefer reports whether body contains a defer statement outside of any
+// nested function literal.
+func hasDefer(body *ast.BlockStmt) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.DeferStmt:
+			found = true
+		case *ast.FuncLit:
+			return false
+		}
+		return !found
+	})
+	return found
+}
+
+// An edit replaces the source bytes in [start, end) with text.
+type edit struct {
+	start, end int
+	text       string
+}
+
+type rewriter struct {
+	fset  *token.FileSet
+	info  *types.Info
+	src   []byte
+	edits []edit
+	ids   int
+}
+
+func (rw *rewriter) offset(p token.Pos) int {
+	return rw.fset.Position(p).Offset
+}
+
+func (rw *rewriter) text(n ast.Node) string {
+	return string(rw.src[rw.offset(n.Pos()):rw.offset(n.End())])
+}
+
+func (rw *rewriter) insert(p token.Pos, text string) {
+	off := rw.offset(p)
+	rw.edits = append(rw.edits, edit{off, off, text})
+}
+
+func (rw *rewriter) replace(n ast.Node, text string) {
+	rw.edits = append(rw.edits, edit{rw.offset(n.Pos()), rw.offset(n.End()), text})
+}
+
+// apply returns the source with all edits applied. Edits never overlap:
+// a statement that is replaced as a whole is not edited inside.
+func (rw *rewriter) apply() []byte {
+	sort.SliceStable(rw.edits, func(i, j int) bool {
+		return rw.edits[i].start < rw.edits[j].start
+	})
+	var b strings.Builder
+	last := 0
+	for _, e := range rw.edits {
+		b.Write(rw.src[last:e.start])
+		b.WriteString(e.text)
+		last = e.end
+	}
+	b.Write(rw.src[last:])
+	return []byte(b.String())
+}
+
+// namedResults are the named results of a traced function.
+type namedResults struct {
+	names []string // the names other than _
+	objs  []types.Object
+	blank bool // some result is named _
+	scope *types.Scope
+}
+
+// assignable reports whether every result can be assigned by its own name at
+// pos, which is not so when a result is named _ or a declaration in an inner
+// block shadows it.
+func (rs *namedResults) assignable(pos token.Pos) bool {
+	if rs.blank || rs.scope == nil {
+		return false
+	}
+	inner := rs.scope.Innermost(pos)
+	for i, name := range rs.names {
+		if _, obj := inner.LookupParent(name, pos); obj == nil || obj != rs.objs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (rw *rewriter) function(fn *ast.FuncDecl) {
+	// Named results are reported when the function returns and again after
+	// the deferred calls have run, so changes made by defers are visible.
+	rs := &namedResults{scope: rw.info.Scopes[fn.Type]}
+	if fn.Type.Results != nil {
+		for _, field := range fn.Type.Results.List {
+			for _, name := range field.Names {
+				if name.Name == "_" {
+					rs.blank = true
+					continue
+				}
+				rs.names = append(rs.names, name.Name)
+				rs.objs = append(rs.objs, rw.info.Defs[name])
+			}
+		}
+	}
+
+	// The first deferred call runs last. It also sees panics that nothing
+	// recovered, logs them and raises them again.
+	args := "recover()"
+	if len(rs.names) > 0 {
+		args += ", " + namedValues(rs.names)
+	}
+	rw.insert(fn.Body.Lbrace+1, fmt.Sprintf(
+		" deferTraceFrame := deferTraceEnter(%q); defer func() { deferTraceFrame.Flush(%s) }();",
+		fn.Name.Name, args))
+
+	rw.block(fn.Body, rs, true)
+	if fn.Type.Results == nil {
+		// Falling off the end is a return too.
+		rw.insert(fn.Body.Rbrace, "deferTraceFrame.Return(); ")
+	}
+}
+
+// namedValues formats names as alternating name, value arguments.
+func namedValues(names []string) string {
+	var parts []string
+	for _, n := range names {
+		parts = append(parts, fmt.Sprintf("%q, %s", n, n))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// block instruments the statements belonging to the traced function itself,
+// skipping function literals other than the ones called by defer. Returns are
+// only logged in the traced function, not in its deferred literals.
+func (rw *rewriter) block(body ast.Node, rs *namedResults, returns bool) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.DeferStmt:
+			rw.deferStmt(n)
+			return false
+		case *ast.ReturnStmt:
+			if returns {
+				return rw.returnStmt(n, rs)
+			}
+		case *ast.CallExpr:
+			rw.builtinCall(n)
+		}
+		return true
+	})
+}
+
+// returnStmt logs a return and reports whether the results may still be
+// edited, which is not the case when the statement is replaced as a whole.
+func (rw *rewriter) returnStmt(ret *ast.ReturnStmt, rs *namedResults) bool {
+	var b strings.Builder
+	b.WriteString("{ ")
+	switch {
+	case len(rs.names) == 0 && !rs.blank || len(ret.Results) == 0:
+		// Nothing to log, or a bare return: the named results already
+		// hold the values being returned.
+		fmt.Fprintf(&b, "deferTraceFrame.Return(%s); ", namedValues(rs.names))
+	case rs.assignable(ret.Pos()):
+		// Assigning the results first makes their values visible before
+		// the deferred calls run. Like return, the assignment evaluates
+		// every operand before it assigns any result.
+		var values []string
+		for _, r := range ret.Results {
+			values = append(values, rw.text(r))
+		}
+		fmt.Fprintf(&b, "%s = %s; ", strings.Join(rs.names, ", "), strings.Join(values, ", "))
+		fmt.Fprintf(&b, "deferTraceFrame.Return(%s); return }", namedValues(rs.names))
+		rw.replace(ret, b.String())
+		return false
+	default:
+		b.WriteString("deferTraceFrame.ReturnHidden(); ")
+	}
+	rw.insert(ret.Pos(), b.String())
+	rw.insert(ret.End(), " }")
+	return true
+}
+
+// builtinCall logs calls to the panic and recover built-ins.
+func (rw *rewriter) builtinCall(call *ast.CallExpr) {
+	id, ok := ast.Unparen(call.Fun).(*ast.Ident)
+	if !ok {
+		return
+	}
+	if _, ok := rw.info.Uses[id].(*types.Builtin); !ok {
+		return
+	}
+	switch id.Name {
+	case "recover":
+		// recover must still be called directly by the deferred function,
+		// so only its result is passed through the tracer.
+		rw.insert(call.Pos(), "deferTraceFrame.Recover(")
+		rw.insert(call.End(), ")")
+	case "panic":
+		if len(call.Args) == 1 {
+			rw.insert(call.Args[0].Pos(), "deferTraceFrame.Panic(")
+			rw.insert(call.Args[0].End(), ")")
+		}
+	}
+}
+
+func (rw *rewriter) deferStmt(d *ast.DeferStmt) {
+	rw.ids++
+	id := rw.ids
+	call := d.Call
+	tmp := func(i int) string { return fmt.Sprintf("deferTraceArg%d_%d", id, i) }
+
+	// Evaluate the arguments once, as defer would, so their values can be
+	// logged. Constants and nil are left in place to keep their typing.
+	var decls, logged, passed []string
+	for i, arg := range call.Args {
+		tv := rw.info.Types[arg]
+		if tv.Value != nil || tv.IsNil() {
+			passed = append(passed, rw.text(arg))
+			logged = append(logged, rw.text(arg))
+			continue
+		}
+		if tuple, ok := tv.Type.(*types.Tuple); ok {
+			var names []string
+			for j := 0; j < tuple.Len(); j++ {
+				names = append(names, tmp(i*100+j))
+			}
+			decls = append(decls, fmt.Sprintf("%s := %s", strings.Join(names, ", "), rw.text(arg)))
+			passed = append(passed, names...)
+			logged = append(logged, names...)
+			continue
+		}
+		decls = append(decls, fmt.Sprintf("%s := %s", tmp(i), rw.text(arg)))
+		passed = append(passed, tmp(i))
+		logged = append(logged, tmp(i))
+	}
+	args := strings.Join(passed, ", ")
+	if call.Ellipsis.IsValid() {
+		args += "..."
+	}
+
+	// Each registration gets its own number, so a defer inside a loop shows
+	// up once per iteration in the timeline.
+	seq := fmt.Sprintf("deferTraceSeq%d", id)
+	register := fmt.Sprintf("%s := deferTraceFrame.Defer(%q, %s)",
+		seq, rw.describe(call), strings.Join(append([]string{"nil"}, logged...), ", "))
+	enter := fmt.Sprintf("deferTraceFrame.Run(%s); defer deferTraceFrame.Done(%s);", seq, seq)
+
+	if lit, ok := ast.Unparen(call.Fun).(*ast.FuncLit); ok {
+		// Instrument the literal in place, so that a recover in its body
+		// is still called directly by the deferred function.
+		rw.insert(d.Pos(), "{ "+joinStmts(decls)+register+"; ")
+		rw.insert(lit.Body.Lbrace+1, " "+enter)
+		rw.block(lit.Body, nil, false)
+		if n := len(call.Args); n > 0 {
+			rw.edits = append(rw.edits, edit{
+				rw.offset(call.Args[0].Pos()), rw.offset(call.Args[n-1].End()), args})
+		}
+		rw.insert(d.End(), " }")
+		return
+	}
+
+	// Any other call is still deferred directly, since a function such as
+	// RecoverTo only works when it is the deferred function itself. A second
+	// defer registered just after it marks the moment it starts to run.
+	fun := rw.text(call.Fun)
+	if len(decls) > 0 && !rw.static(call.Fun) {
+		// defer evaluates the function value before the arguments.
+		fv := fmt.Sprintf("deferTraceFunc%d", id)
+		decls = append([]string{fmt.Sprintf("%s := %s", fv, fun)}, decls...)
+		fun = fv
+	}
+	rw.replace(d, fmt.Sprintf("{ %s%s; defer %s(%s); defer deferTraceFrame.Run(%s) }",
+		joinStmts(decls), register, fun, args, seq))
+}
+
+// static reports whether fun names a declared function or a built-in, whose
+// value cannot change while the arguments are evaluated. A built-in or a
+// generic function cannot be stored in a variable either.
+func (rw *rewriter) static(fun ast.Expr) bool {
+	switch f := ast.Unparen(fun).(type) {
+	case *ast.Ident:
+		switch rw.info.Uses[f].(type) {
+		case *types.Func, *types.Builtin:
+			return true
+		}
+	case *ast.SelectorExpr:
+		if rw.info.Selections[f] == nil {
+			// A qualified identifier such as fmt.Println.
+			return rw.static(f.Sel)
+		}
+	case *ast.IndexExpr:
+		return rw.static(f.X)
+	case *ast.IndexListExpr:
+		return rw.static(f.X)
+	}
+	return false
+}
+
+func joinStmts(stmts []string) string {
+	if len(stmts) == 0 {
+		return ""
+	}
+	return strings.Join(stmts, "; ") + "; "
+}
+
+// describe returns a one-line form of the deferred call for the timeline.
+func (rw *rewriter) describe(call *ast.CallExpr) string {
+	s := rw.text(call)
+	if lit, ok := ast.Unparen(call.Fun).(*ast.FuncLit); ok {
+		var args []string
+		for _, a := range call.Args {
+			args = append(args, rw.text(a))
+		}
+		s = rw.text(lit.Type) + " {...}(" + strings.Join(args, ", ") + ")"
+	}
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > 48 {
+		s = s[:45] + "..."
+	}
+	return s
+}
+
+// runtimeSrc is added to the rewritten program. It records the events of
+// each call of a traced function and prints them when the call returns.
+const runtimeSrc = `package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+type deferTraceEvent struct {
+	kind, detail string
+	pending      []int
+}
+
+type deferTraceFrameT struct {
+	mu       sync.Mutex
+	name     string
+	events   []deferTraceEvent
+	pending  []int
+	describe map[int]string
+	next     int
+	returned []interface{}
+	hidden   bool // a return could not show the results
+
+	// unwinding is set once the function returns or panics. raised is the
+	// number of a panic event whose value is not known yet.
+	unwinding bool
+	raised    int
+}
+
+func deferTraceEnter(name string) *deferTraceFrameT {
+	return &deferTraceFrameT{name: name, describe: make(map[int]string)}
+}
+
+func (f *deferTraceFrameT) log(kind, detail string) {
+	f.events = append(f.events, deferTraceEvent{kind, detail, append([]int(nil), f.pending...)})
+}
+
+func (f *deferTraceFrameT) Defer(call string, args ...interface{}) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.next++
+	id := f.next
+	f.describe[id] = call
+	f.pending = append(f.pending, id)
+	detail := fmt.Sprintf("#%d %s", id, call)
+	if len(args) > 1 {
+		var vals []string
+		for _, a := range args[1:] {
+			vals = append(vals, deferTraceFormat(a))
+		}
+		detail += "  args: " + strings.Join(vals, ", ")
+	}
+	f.log("defer", detail)
+	return id
+}
+
+func (f *deferTraceFrameT) Run(id int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// A deferred call that runs before any return or panic call was logged
+	// was started by a panic raised elsewhere, such as a division by zero
+	// or a panic in a callee. Its value is known once something recovers it.
+	if !f.unwinding {
+		f.unwinding = true
+		f.log("panic", "(not raised here; value shown when recovered)")
+		f.raised = len(f.events)
+	}
+	for i := len(f.pending) - 1; i >= 0; i-- {
+		if f.pending[i] == id {
+			f.pending = append(f.pending[:i], f.pending[i+1:]...)
+			break
+		}
+	}
+	f.log("run", fmt.Sprintf("#%d %s", id, f.describe[id]))
+}
+
+func (f *deferTraceFrameT) Done(id int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.log("done", fmt.Sprintf("#%d", id))
+}
+
+func (f *deferTraceFrameT) Return(results ...interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.returned = results
+	f.unwinding = true
+	f.log("return", deferTraceValues(results))
+}
+
+// ReturnHidden logs a return whose results are not logged, because one is
+// named _ or is shadowed where the return is.
+func (f *deferTraceFrameT) ReturnHidden() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hidden = true
+	f.unwinding = true
+	f.log("return", "(results not visible here)")
+}
+
+func (f *deferTraceFrameT) Panic(v interface{}) interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.unwinding = true
+	f.log("panic", deferTraceFormat(v))
+	return v
+}
+
+func (f *deferTraceFrameT) Recover(v interface{}) interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if v != nil {
+		f.fill(v)
+		f.log("recover", deferTraceFormat(v))
+	} else {
+		f.log("recover", "nil (not panicking)")
+	}
+	return v
+}
+
+// fill completes the event of a panic raised elsewhere with its value.
+func (f *deferTraceFrameT) fill(v interface{}) {
+	if f.raised > 0 {
+		f.events[f.raised-1].detail = deferTraceFormat(v)
+		f.raised = 0
+	}
+}
+
+// Flush prints the timeline. It runs after every other deferred call, with
+// the value of a panic that none of them recovered, and raises that panic
+// again once the timeline is printed.
+func (f *deferTraceFrameT) Flush(recovered interface{}, results ...interface{}) {
+	if recovered != nil {
+		defer panic(recovered)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if recovered != nil {
+		f.fill(recovered)
+		f.log("unrecovered", deferTraceFormat(recovered))
+	}
+	if f.raised > 0 {
+		f.events[f.raised-1].detail = "(not raised here; recovered out of view, or runtime.Goexit)"
+	}
+	w := tabwriter.NewWriter(os.Stderr, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "--- defer trace: %s\n", f.name)
+	fmt.Fprintln(w, "step\tevent\tdetail\tpending (top last)")
+	for i, e := range f.events {
+		var ids []string
+		for _, id := range e.pending {
+			ids = append(ids, fmt.Sprint("#", id))
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t[%s]\n", i+1, e.kind, e.detail, strings.Join(ids, " "))
+	}
+	for i := 0; i+1 < len(results); i += 2 {
+		before := "(no return)"
+		if f.hidden {
+			before = "?"
+		} else if i+1 < len(f.returned) {
+			before = deferTraceFormat(f.returned[i+1])
+		}
+		fmt.Fprintf(w, "result\t%v\t%s -> %s\t\n", results[i], before, deferTraceFormat(results[i+1]))
+	}
+	w.Flush()
+}
+
+func deferTraceValues(nv []interface{}) string {
+	var parts []string
+	for i := 0; i+1 < len(nv); i += 2 {
+		parts = append(parts, fmt.Sprintf("%v=%s", nv[i], deferTraceFormat(nv[i+1])))
+	}
+	return strings.Join(parts, " ")
+}
+
+// deferTraceFormat keeps long values, such as stack traces, to one short line.
+func deferTraceFormat(v interface{}) string {
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = fmt.Sprintf("%q", v)
+	case error:
+		s = v.Error()
+	default:
+		s = fmt.Sprintf("%v", v)
+	}
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > 40 {
+		s = s[:37] + "..."
+	}
+	return s
+}
+`
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// sample has a shadowed named result, a deferred function value whose
+// arguments change it, and a panic raised by the runtime.
+const sample = `package main
+
+import "fmt"
+
+func named() (n int, err error) {
+	defer fmt.Println("n is", n)
+	if n := 2; n > 1 {
+		return n, nil
+	}
+	return 1, nil
+}
+
+func pick(fs []func(int)) {
+	i := 0
+	defer fs[i](next(&i))
+	defer fmt.Println(next(&i))
+}
+
+func next(i *int) int { *i++; return *i }
+
+func div(a, b int) (q int) {
+	defer func() {
+		if r := recover(); r != nil {
+			q = -1
+		}
+	}()
+	return a / b
+}
+
+func main() {
+	named()
+	pick([]func(int){func(i int) { fmt.Println("fs[0]", i) }, func(i int) { fmt.Println("fs[1]", i) }})
+	fmt.Println(div(1, 0))
+}
+`
+
+const sampleTraced = `package main
+
+import "fmt"
+
+func named() (n int, err error) { deferTraceFrame := deferTraceEnter("named"); defer func() { deferTraceFrame.Flush(recover(), "n", n, "err", err) }();
+	{ deferTraceArg1_1 := n; deferTraceSeq1 := deferTraceFrame.Defer("fmt.Println(\"n is\", n)", nil, "n is", deferTraceArg1_1); defer fmt.Println("n is", deferTraceArg1_1); defer deferTraceFrame.Run(deferTraceSeq1) }
+	if n := 2; n > 1 {
+		{ deferTraceFrame.ReturnHidden(); return n, nil }
+	}
+	{ n, err = 1, nil; deferTraceFrame.Return("n", n, "err", err); return }
+}
+
+func pick(fs []func(int)) { deferTraceFrame := deferTraceEnter("pick"); defer func() { deferTraceFrame.Flush(recover()) }();
+	i := 0
+	{ deferTraceFunc2 := fs[i]; deferTraceArg2_0 := next(&i); deferTraceSeq2 := deferTraceFrame.Defer("fs[i](next(&i))", nil, deferTraceArg2_0); defer deferTraceFunc2(deferTraceArg2_0); defer deferTraceFrame.Run(deferTraceSeq2) }
+	{ deferTraceArg3_0 := next(&i); deferTraceSeq3 := deferTraceFrame.Defer("fmt.Println(next(&i))", nil, deferTraceArg3_0); defer fmt.Println(deferTraceArg3_0); defer deferTraceFrame.Run(deferTraceSeq3) }
+deferTraceFrame.Return(); }
+
+func next(i *int) int { *i++; return *i }
+
+func div(a, b int) (q int) { deferTraceFrame := deferTraceEnter("div"); defer func() { deferTraceFrame.Flush(recover(), "q", q) }();
+	{ deferTraceSeq4 := deferTraceFrame.Defer("func() {...}()", nil); defer func() { deferTraceFrame.Run(deferTraceSeq4); defer deferTraceFrame.Done(deferTraceSeq4);
+		if r := deferTraceFrame.Recover(recover()); r != nil {
+			q = -1
+		}
+	}() }
+	{ q = a / b; deferTraceFrame.Return("q", q); return }
+}
+
+func main() {
+	named()
+	pick([]func(int){func(i int) { fmt.Println("fs[0]", i) }, func(i int) { fmt.Println("fs[1]", i) }})
+	fmt.Println(div(1, 0))
+}
+`
+
+func TestInstrument(t *testing.T) {
+	out, err := instrument([]string{"sample.go"}, [][]byte{[]byte(sample)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(out[0]); got != sampleTraced {
+		t.Errorf("instrumented source:\n%s\nwant:\n%s", got, sampleTraced)
+	}
+
+	// A function that does not exist leaves nothing to trace.
+	if _, err := instrument([]string{"sample.go"}, [][]byte{[]byte(sample)}, map[string]bool{"nope": true}); err == nil {
+		t.Error("instrument with no function to trace succeeded")
+	}
+}
+
+const sampleStdout = `n is 0
+2
+fs[0] 1
+-1
+`
+
+const sampleTimeline = `--- defer trace: named
+step    event   detail                                      pending (top last)
+1       defer   #1 fmt.Println("n is", n)  args: "n is", 0  [#1]
+2       return  (results not visible here)                  [#1]
+3       run     #1 fmt.Println("n is", n)                   []
+result  n       ? -> 2                                      
+result  err     ? -> <nil>                                  
+--- defer trace: pick
+step  event   detail                             pending (top last)
+1     defer   #1 fs[i](next(&i))  args: 1        [#1]
+2     defer   #2 fmt.Println(next(&i))  args: 2  [#1 #2]
+3     return                                     [#1 #2]
+4     run     #2 fmt.Println(next(&i))           [#1]
+5     run     #1 fs[i](next(&i))                 []
+--- defer trace: div
+step    event    detail                                 pending (top last)
+1       defer    #1 func() {...}()                      [#1]
+2       panic    runtime error: integer divide by zero  [#1]
+3       run      #1 func() {...}()                      []
+4       recover  runtime error: integer divide by zero  []
+5       done     #1                                     []
+result  q        (no return) -> -1                      
+`
+
+// TestTraceRun builds and runs the instrumented sample: the rewritten
+// returns must compile even where a result is shadowed.
+func TestTraceRun(t *testing.T) {
+	out, err := instrument([]string{"sample.go"}, [][]byte{[]byte(sample)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "sample.go"), out[0], 0o644)
+	os.WriteFile(filepath.Join(dir, "zz_defertrace.go"), []byte(runtimeSrc), 0o644)
+	cmd := exec.Command("go", "run", "sample.go", "zz_defertrace.go")
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("go run: %v\n%s", err, stderr.String())
+	}
+	if got := stdout.String(); got != sampleStdout {
+		t.Errorf("stdout:\n%s\nwant:\n%s", got, sampleStdout)
+	}
+	if got := stderr.String(); got != sampleTimeline {
+		t.Errorf("timeline:\n%s\nwant:\n%s", got, sampleTimeline)
+	}
+}
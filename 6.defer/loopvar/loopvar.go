@@ -0,0 +1,352 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Before Go 1.22 a for loop had one variable shared by all iterations, so a
+// closure created in the loop saw its final value. Since Go 1.22 each iteration
+// has its own variable. Which rule applies depends on the go version of the
+// module that contains the file, which is why d in 6.defer prints
+// "closure i = 4" with older versions and 0..3 with newer ones.
+
+// loopvar runs a lesson under both rules and reports the output lines that
+// change, then lists the closures that capture a loop variable.
+//
+// Usage:
+//
+//	go run 6.defer/loopvar/loopvar.go [-static] 6.defer
+func main() {
+	static := flag.Bool("static", false, "only run the static pass")
+	flag.Parse()
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil || len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "loopvar: no Go files in", dir)
+		os.Exit(2)
+	}
+
+	// A lesson that uses features newer than Go 1.22 cannot be built with
+	// the old rule at all; the static pass still applies to it.
+	status := 0
+	if !*static {
+		if err := compare(dir, files); err != nil {
+			fmt.Fprintln(os.Stderr, "loopvar: cannot compare outputs:", err)
+			status = 1
+		}
+	}
+	if err := check(os.Stdout, files); err != nil {
+		fmt.Fprintln(os.Stderr, "loopvar:", err)
+		status = 1
+	}
+	os.Exit(status)
+}
+
+// compare runs the files once with the declared go version and once with
+// the version on the other side of Go 1.22, and prints the differences.
+func compare(dir string, files []string) error {
+	declared := declaredVersion(dir)
+	other := "1.22"
+	if atLeast122(declared) {
+		other = "1.21"
+	}
+
+	// Build both sides even if the first fails, to say which could not build.
+	before, errBefore := run(files, declared)
+	after, errAfter := run(files, other)
+	if err := errors.Join(errBefore, errAfter); err != nil {
+		return err
+	}
+
+	fmt.Printf("go %s vs go %s\n", declared, other)
+	printDiff(os.Stdout, lines(before), lines(after))
+	return nil
+}
+
+// An edit is one line of a line diff: op is ' ' for a line both outputs
+// share, '-' for a line only in the first and '+' for one only in the second.
+// n is the line's number in the output it comes from.
+type edit struct {
+	op   byte
+	n    int
+	text string
+}
+
+// diffLines returns the edits that turn a into b, keeping a longest common
+// subsequence of lines, so a line added or removed in one output does not
+// make every later line look changed.
+func diffLines(a, b []string) []edit {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', i + 1, a[i]})
+			i++
+			j++
+		case j == len(b) || i < len(a) && lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, edit{'-', i + 1, a[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', j + 1, b[j]})
+			j++
+		}
+	}
+	return edits
+}
+
+// printDiff writes the lines that differ between a and b, numbered in the
+// output they come from, and a summary.
+func printDiff(w io.Writer, a, b []string) {
+	removed, added := 0, 0
+	for _, e := range diffLines(a, b) {
+		switch e.op {
+		case '-':
+			removed++
+		case '+':
+			added++
+		default:
+			continue
+		}
+		fmt.Fprintf(w, "%c line %d: %q\n", e.op, e.n, e.text)
+	}
+	fmt.Fprintf(w, "%d lines removed, %d added\n", removed, added)
+}
+
+var goDirective = regexp.MustCompile(`(?m)^go\s+(\d+\.\d+)`)
+
+// declaredVersion returns the go version of the nearest go.mod above dir.
+// Files outside a module are built with the toolchain's own version.
+func declaredVersion(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err == nil {
+		for d := abs; ; d = filepath.Dir(d) {
+			if data, err := os.ReadFile(filepath.Join(d, "go.mod")); err == nil {
+				if m := goDirective.FindSubmatch(data); m != nil {
+					return string(m[1])
+				}
+				break
+			}
+			if filepath.Dir(d) == d {
+				break
+			}
+		}
+	}
+	out, err := exec.Command("go", "env", "GOVERSION").Output()
+	if err != nil {
+		return "1.22"
+	}
+	v := strings.TrimPrefix(strings.TrimSpace(string(out)), "go")
+	if parts := strings.SplitN(v, ".", 3); len(parts) >= 2 {
+		return parts[0] + "." + parts[1]
+	}
+	return v
+}
+
+func atLeast122(v string) bool {
+	parts := strings.SplitN(v, ".", 3)
+	if len(parts) < 2 {
+		return true
+	}
+	major, _ := strconv.Atoi(parts[0])
+	minor, _ := strconv.Atoi(parts[1])
+	return major > 1 || major == 1 && minor >= 22
+}
+
+// run copies the files into a temporary module declaring the given go
+// version and returns the program's standard output.
+func run(files []string, version string) ([]byte, error) {
+	tmp, err := os.MkdirTemp("", "loopvar")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	mod := fmt.Sprintf("module loopvar\n\ngo %s\n", version)
+	if err := os.WriteFile(filepath.Join(tmp, "go.mod"), []byte(mod), 0o644); err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(tmp, filepath.Base(f)), data, 0o644); err != nil {
+			return nil, err
+		}
+	}
+
+	cmd := exec.Command("go", "run", ".")
+	cmd.Dir = tmp
+	cmd.Env = append(os.Environ(), "GOTOOLCHAIN=local", "GOFLAGS=")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		// The first lines are enough to see why, e.g. "requires go1.23 or later".
+		msg := lines(stderr.Bytes())
+		if len(msg) > 5 {
+			msg = append(msg[:5], "...")
+		}
+		return nil, fmt.Errorf("go %s build or run failed: %v\n\t%s", version, err, strings.Join(msg, "\n\t"))
+	}
+	return out, nil
+}
+
+func lines(b []byte) []string {
+	s := strings.TrimRight(string(b), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// check reports to w every function literal that refers to a variable
+// declared by an enclosing for or range statement.
+func check(w io.Writer, files []string) error {
+	fset := token.NewFileSet()
+	var parsed []*ast.File
+	for _, f := range files {
+		file, err := parser.ParseFile(fset, f, nil, 0)
+		if err != nil {
+			return err
+		}
+		parsed = append(parsed, file)
+	}
+
+	info := &types.Info{
+		Defs: make(map[*ast.Ident]types.Object),
+		Uses: make(map[*ast.Ident]types.Object),
+	}
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		// Keep going on errors such as missing third-party imports;
+		// the identifiers that matter here still resolve.
+		Error: func(error) {},
+	}
+	conf.Check("main", fset, parsed, info)
+
+	for _, file := range parsed {
+		ast.Inspect(file, func(n ast.Node) bool {
+			var vars []types.Object
+			var body *ast.BlockStmt
+			switch loop := n.(type) {
+			case *ast.ForStmt:
+				if init, ok := loop.Init.(*ast.AssignStmt); ok && init.Tok == token.DEFINE {
+					vars = defined(info, init.Lhs...)
+				}
+				body = loop.Body
+			case *ast.RangeStmt:
+				if loop.Tok == token.DEFINE {
+					vars = defined(info, loop.Key, loop.Value)
+				}
+				body = loop.Body
+			default:
+				return true
+			}
+			if len(vars) > 0 {
+				report(w, fset, info, body, vars)
+			}
+			return true
+		})
+	}
+	return nil
+}
+
+func defined(info *types.Info, exprs ...ast.Expr) []types.Object {
+	var objs []types.Object
+	for _, e := range exprs {
+		if id, ok := e.(*ast.Ident); ok && id.Name != "_" {
+			if obj := info.Defs[id]; obj != nil {
+				objs = append(objs, obj)
+			}
+		}
+	}
+	return objs
+}
+
+func report(w io.Writer, fset *token.FileSet, info *types.Info, body *ast.BlockStmt, vars []types.Object) {
+	// Remember which function literals are called by defer or go,
+	// so the report can say when the capture runs after the loop.
+	kind := make(map[*ast.FuncLit]string)
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.DeferStmt:
+			if lit, ok := s.Call.Fun.(*ast.FuncLit); ok {
+				kind[lit] = "deferred closure"
+			}
+		case *ast.GoStmt:
+			if lit, ok := s.Call.Fun.(*ast.FuncLit); ok {
+				kind[lit] = "goroutine"
+			}
+		}
+		return true
+	})
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		lit, ok := n.(*ast.FuncLit)
+		if !ok {
+			return true
+		}
+		seen := make(map[types.Object]bool)
+		ast.Inspect(lit.Body, func(n ast.Node) bool {
+			id, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			obj := info.Uses[id]
+			if obj == nil || seen[obj] {
+				return true
+			}
+			for _, v := range vars {
+				if obj == v {
+					seen[obj] = true
+					k := kind[lit]
+					if k == "" {
+						k = "closure"
+					}
+					fmt.Fprintf(w, "%s: %s captures loop variable %s\n",
+						fset.Position(id.Pos()), k, id.Name)
+				}
+			}
+			return true
+		})
+		// Nested literals are visited with the outer one.
+		return false
+	})
+}
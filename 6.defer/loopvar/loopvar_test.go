@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrintDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"same", "x\ny", "x\ny", "0 lines removed, 0 added\n"},
+		{"changed", "i = 4\ni = 4\ndone", "i = 0\ni = 1\ndone",
+			`- line 1: "i = 4"
+- line 2: "i = 4"
++ line 1: "i = 0"
++ line 2: "i = 1"
+2 lines removed, 2 added
+`},
+		// One extra line must not shift every later line.
+		{"inserted", "a\nb\nc\nd", "a\nx\nb\nc\nd",
+			`+ line 2: "x"
+0 lines removed, 1 added
+`},
+		{"deleted", "a\nb\nc\nd", "a\nc\nd",
+			`- line 2: "b"
+1 lines removed, 0 added
+`},
+		{"empty", "", "a", `+ line 1: "a"
+0 lines removed, 1 added
+`},
+	}
+	for _, tt := range tests {
+		var sb strings.Builder
+		printDiff(&sb, lines([]byte(tt.a)), lines([]byte(tt.b)))
+		if got := sb.String(); got != tt.want {
+			t.Errorf("%s:\n%s\nwant:\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+const loopSource = `package main
+
+import "fmt"
+
+func main() {
+	for i := 0; i < 3; i++ {
+		defer func() { fmt.Println(i) }()
+		go func() { fmt.Println(i) }()
+		f := func() { fmt.Println(i, i) }
+		f()
+	}
+	for k, v := range []int{1} {
+		_ = func() int { return k }
+		fmt.Println(v)
+	}
+	var j int
+	for j = 0; j < 3; j++ {
+		defer func() { fmt.Println(j) }()
+	}
+}
+`
+
+func TestCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "loop.go")
+	if err := os.WriteFile(path, []byte(loopSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	if err := check(&sb, []string{path}); err != nil {
+		t.Fatal(err)
+	}
+	// j is declared outside its loop, so it is shared under both rules.
+	want := path + `:7:30: deferred closure captures loop variable i
+` + path + `:8:27: goroutine captures loop variable i
+` + path + `:9:29: closure captures loop variable i
+` + path + `:13:27: closure captures loop variable k
+`
+	if got := sb.String(); got != want {
+		t.Errorf("check:\n%s\nwant:\n%s", got, want)
+	}
+}
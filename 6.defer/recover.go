@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+)
+
+// The recover block in b is the usual way to stop a panic, but writing it by
+// hand every time is easy to get wrong. The helpers below turn a panic into an
+// ordinary error value that can be returned, wrapped and inspected.
+
+// PanicError is the error produced when a panic is recovered.
+// It keeps the value passed to panic, the stack of the panicking goroutine
+// and that goroutine's ID.
+type PanicError struct {
+	Value       interface{}
+	Stack       []byte
+	GoroutineID int64
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic in goroutine %d: %v", e.GoroutineID, e.Value)
+}
+
+// Unwrap returns the panic value if it is itself an error, so errors.Is and
+// errors.As can look through a PanicError, e.g. for a runtime.Error.
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+func newPanicError(v interface{}) *PanicError {
+	stack := debug.Stack()
+	return &PanicError{Value: v, Stack: stack, GoroutineID: goroutineID(stack)}
+}
+
+// goroutineID parses the ID from the first line of a stack trace,
+// which looks like "goroutine 18 [running]:".
+func goroutineID(stack []byte) int64 {
+	line := bytes.TrimPrefix(stack, []byte("goroutine "))
+	if i := bytes.IndexByte(line, ' '); i >= 0 {
+		line = line[:i]
+	}
+	id, err := strconv.ParseInt(string(line), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return id
+}
+
+// RecoverTo must be called directly by defer. If the surrounding function is
+// panicking, the panic is stopped and stored in *errp as a *PanicError.
+//
+//	func f() (err error) {
+//		defer RecoverTo(&err)
+//		...
+//	}
+//
+// Like recover itself, it has no effect on runtime.Goexit, which keeps
+// unwinding the goroutine.
+func RecoverTo(errp *error) {
+	if v := recover(); v != nil {
+		*errp = newPanicError(v)
+	}
+}
+
+// Try calls fn and returns a *PanicError if fn panics, or nil otherwise.
+// If fn calls runtime.Goexit, Try does not return: the goroutine keeps
+// exiting exactly as if fn had been called directly.
+func Try(fn func()) (err error) {
+	normalReturn := false
+	defer func() {
+		if normalReturn {
+			return
+		}
+		v := recover()
+		if v == nil {
+			// Not a panic, so fn called runtime.Goexit; let it continue.
+			return
+		}
+		err = newPanicError(v)
+	}()
+	fn()
+	normalReturn = true
+	return nil
+}
+
+// DefaultPanicHandler prints a panic recovered by Go and its stack to
+// standard error.
+func DefaultPanicHandler(err *PanicError) {
+	fmt.Fprintf(os.Stderr, "%v\n%s", err, err.Stack)
+}
+
+// Go starts fn in a new goroutine. A panic in fn is passed to handler, or to
+// DefaultPanicHandler if handler is nil, instead of crashing the whole
+// program. Each goroutine gets its handler when it starts, so there is no
+// shared setting to change while others run.
+func Go(fn func(), handler func(*PanicError)) {
+	if handler == nil {
+		handler = DefaultPanicHandler
+	}
+	go func() {
+		if err := Try(fn); err != nil {
+			var pe *PanicError
+			if errors.As(err, &pe) {
+				handler(pe)
+			}
+		}
+	}()
+}
+
+func recoverMain() {
+	err := Try(func() { panic("Panic in Try") })
+	fmt.Println(err != nil) // true
+
+	var pe *PanicError
+	if errors.As(err, &pe) {
+		fmt.Println(pe.Value) // Panic in Try
+	}
+
+	// Runtime errors such as an integer divide by zero are errors themselves,
+	// so they can be found again through the PanicError.
+	_, err = divide(1, 0)
+	var re runtime.Error
+	fmt.Println(errors.As(err, &re)) // true
+
+	done := make(chan struct{})
+	Go(func() { panic("Panic in Go") }, func(err *PanicError) {
+		fmt.Println("Recovered:", err.Value)
+		close(done)
+	})
+	<-done // Recovered: Panic in Go
+}
+
+func divide(a, b int) (q int, err error) {
+	defer RecoverTo(&err)
+	return a / b, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestTry(t *testing.T) {
+	if err := Try(func() {}); err != nil {
+		t.Fatalf("Try(no panic) = %v, want nil", err)
+	}
+
+	err := Try(func() { panic("boom") })
+	var pe *PanicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Try(panic) = %v, want a *PanicError", err)
+	}
+	if pe.Value != "boom" {
+		t.Errorf("Value = %v, want boom", pe.Value)
+	}
+	if len(pe.Stack) == 0 || pe.GoroutineID <= 0 {
+		t.Errorf("Stack has %d bytes, GoroutineID = %d; want both set", len(pe.Stack), pe.GoroutineID)
+	}
+}
+
+func TestRecoverTo(t *testing.T) {
+	q, err := divide(6, 3)
+	if q != 2 || err != nil {
+		t.Fatalf("divide(6, 3) = %d, %v; want 2, nil", q, err)
+	}
+
+	_, err = divide(1, 0)
+	var pe *PanicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("divide(1, 0) error = %v, want a *PanicError", err)
+	}
+	// The runtime error is found through Unwrap.
+	var re runtime.Error
+	if !errors.As(err, &re) {
+		t.Errorf("errors.As(%v, *runtime.Error) = false, want true", err)
+	}
+}
+
+func TestTryGoexit(t *testing.T) {
+	var (
+		returned bool
+		err      error
+	)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		err = Try(runtime.Goexit)
+		returned = true
+	}()
+	<-done
+	if returned {
+		t.Fatalf("Try(runtime.Goexit) returned %v; want the goroutine to exit", err)
+	}
+}
+
+func TestGo(t *testing.T) {
+	var wg sync.WaitGroup
+	got := make(chan interface{}, 2)
+	for _, v := range []string{"first", "second"} {
+		wg.Add(1)
+		Go(func() { panic(v) }, func(err *PanicError) {
+			defer wg.Done()
+			got <- err.Value
+		})
+	}
+	wg.Wait()
+	close(got)
+	seen := map[interface{}]bool{}
+	for v := range got {
+		seen[v] = true
+	}
+	if !seen["first"] || !seen["second"] {
+		t.Errorf("handlers got %v, want first and second", seen)
+	}
+}
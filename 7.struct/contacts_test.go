@@ -0,0 +1,223 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func writeCard(t *testing.T, c Card, version string) string {
+	t.Helper()
+	var b strings.Builder
+	if err := WriteVCard(&b, c, version); err != nil {
+		t.Fatalf("WriteVCard(%s): %v", version, err)
+	}
+	return b.String()
+}
+
+func parseCard(t *testing.T, vcf string) Card {
+	t.Helper()
+	cards, err := ParseVCards(strings.NewReader(vcf))
+	if err != nil {
+		t.Fatalf("ParseVCards: %v\n%s", err, vcf)
+	}
+	if len(cards) != 1 {
+		t.Fatalf("ParseVCards returned %d cards, want 1", len(cards))
+	}
+	return cards[0]
+}
+
+func TestWriteVCardFolding(t *testing.T) {
+	// 李 is three octets, so a fold at a fixed offset would split it.
+	c := Card{FormattedName: strings.Repeat("李", 60) + strings.Repeat("a", 100)}
+	vcf := writeCard(t, c, "4.0")
+	folded := 0
+	for _, line := range strings.Split(strings.TrimSuffix(vcf, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line has %d octets, want at most 75: %q", len(line), line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("fold splits a UTF-8 sequence: %q", line)
+		}
+		if strings.HasPrefix(line, " ") {
+			folded++
+		}
+	}
+	if folded < 3 {
+		t.Errorf("got %d continuation lines, want at least 3", folded)
+	}
+	if got := parseCard(t, vcf).FormattedName; got != c.FormattedName {
+		t.Errorf("FN after unfolding = %q, want %q", got, c.FormattedName)
+	}
+}
+
+func TestVCardEscaping(t *testing.T) {
+	c := Card{
+		FormattedName: `Li, Lei; \ the "first"`,
+		Role:          "teacher\nhead of year",
+		Contact: Contact{Addresses: []Address{{
+			Street: "No. 1, Zhongguancun Street; Building 2", City: "Beijing",
+		}}},
+	}
+	vcf := writeCard(t, c, "3.0")
+	for _, want := range []string{
+		`FN:Li\, Lei\; \\ the "first"`,
+		`ROLE:teacher\nhead of year`,
+		`ADR:;;No. 1\, Zhongguancun Street\; Building 2;Beijing;;;`,
+	} {
+		if !strings.Contains(vcf, want+"\r\n") {
+			t.Errorf("vCard lacks line %q:\n%s", want, vcf)
+		}
+	}
+	got := parseCard(t, vcf)
+	if got.FormattedName != c.FormattedName || got.Role != c.Role ||
+		got.Contact.Addresses[0].Street != c.Contact.Addresses[0].Street {
+		t.Errorf("parsed %+v, want %+v", got, c)
+	}
+}
+
+func TestVCardTypeLists(t *testing.T) {
+	c := Card{Contact: Contact{Phones: []Phone{{Number: "18833445566", Types: []string{"cell", "voice"}}}}}
+	for _, tt := range []struct{ version, line string }{
+		{"3.0", "TEL;TYPE=cell,voice:18833445566"},
+		{"4.0", `TEL;TYPE="cell,voice":18833445566`},
+	} {
+		vcf := writeCard(t, c, tt.version)
+		if !strings.Contains(vcf, tt.line+"\r\n") {
+			t.Errorf("%s: vCard lacks line %q:\n%s", tt.version, tt.line, vcf)
+		}
+	}
+
+	// Every way of writing the same two types parses alike.
+	want := []string{"cell", "voice"}
+	for _, line := range []string{
+		`TEL;TYPE="cell,voice":1`,
+		`TEL;TYPE=cell,voice:1`,
+		`TEL;TYPE=CELL;TYPE=VOICE:1`,
+		`TEL;CELL;VOICE:1`,
+	} {
+		got := parseCard(t, "BEGIN:VCARD\r\n"+line+"\r\nEND:VCARD\r\n").Contact.Phones[0].Types
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Types = %q, want %q", line, got, want)
+		}
+	}
+}
+
+func TestVCardGender(t *testing.T) {
+	c := Card{FormattedName: "Han Meimei", Gender: "female"}
+	for _, tt := range []struct{ version, line string }{
+		{"3.0", "X-GENDER:F"},
+		{"4.0", "GENDER:F"},
+	} {
+		vcf := writeCard(t, c, tt.version)
+		if !strings.Contains(vcf, "\r\n"+tt.line+"\r\n") {
+			t.Errorf("%s: vCard lacks line %q:\n%s", tt.version, tt.line, vcf)
+		}
+		if got := parseCard(t, vcf).Gender; got != "female" {
+			t.Errorf("%s: Gender = %q, want female", tt.version, got)
+		}
+	}
+	// GENDER may carry an identity after the sex component.
+	if got := parseCard(t, "BEGIN:VCARD\r\nGENDER:M;boy\r\nEND:VCARD\r\n").Gender; got != "male" {
+		t.Errorf("GENDER:M;boy gives Gender %q, want male", got)
+	}
+}
+
+func TestVCardRoundTrip(t *testing.T) {
+	c := parseCard(t, sampleVCard)
+	c.Birthday = "1994-05-06"
+	for _, version := range []string{"3.0", "4.0"} {
+		again := parseCard(t, writeCard(t, c, version))
+		if d := Diff(c, again); d != nil {
+			t.Errorf("%s: round trip changed the card:\n%v", version, d)
+		}
+	}
+}
+
+func TestParseVCardsErrors(t *testing.T) {
+	for _, vcf := range []string{
+		"FN:Nobody\r\n",
+		"BEGIN:VCARD\r\nFN:Li Lei\r\n",
+		"BEGIN:VCARD\r\nno colon here\r\nEND:VCARD\r\n",
+	} {
+		if _, err := ParseVCards(strings.NewReader(vcf)); err == nil {
+			t.Errorf("ParseVCards(%q) succeeded, want an error", vcf)
+		}
+	}
+	if err := WriteVCard(new(strings.Builder), Card{}, "2.1"); err == nil {
+		t.Error("WriteVCard(2.1) succeeded, want an error")
+	}
+}
+
+func TestCardHumanAge(t *testing.T) {
+	tests := []struct {
+		birthday, now string
+		want          int
+	}{
+		{"1994-05-06", "2024-05-05", 29},
+		{"1994-05-06", "2024-05-06", 30},
+		// March 1 is day 61 in a leap year and day 60 otherwise.
+		{"1995-03-01", "2024-02-29", 28},
+		{"1995-03-01", "2024-03-01", 29},
+		{"1996-03-01", "2023-02-28", 26},
+		{"1996-03-01", "2023-03-01", 27},
+		{"1996-12-31", "2024-12-30", 27},
+		{"1996-12-31", "2024-12-31", 28},
+		// Born on February 29.
+		{"2000-02-29", "2023-02-28", 22},
+		{"2000-02-29", "2023-03-01", 23},
+		{"2000-02-29", "2024-02-29", 24},
+		{"", "2024-01-01", 0},
+	}
+	for _, tt := range tests {
+		now, _ := time.Parse("2006-01-02", tt.now)
+		c := Card{Birthday: tt.birthday}
+		if got := c.Human(now).Age; got != tt.want {
+			t.Errorf("born %s, on %s: age %d, want %d", tt.birthday, tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestCardFromHuman(t *testing.T) {
+	h := Human{Name: "Li Lei", Gender: "male"}
+	h.Contact.Address, h.Contact.Tel = "Beijing", "18833445566"
+	c := CardFromHuman(h)
+	if c.FormattedName != "Li Lei" || c.GivenName != "" || c.FamilyName != "" {
+		t.Errorf("FN %q, N %q;%q; want only FN set", c.FormattedName, c.FamilyName, c.GivenName)
+	}
+	if got := c.Human(time.Now()); got != h {
+		t.Errorf("Human() = %+v, want %+v", got, h)
+	}
+}
+
+func TestContactsImport(t *testing.T) {
+	dir := t.TempDir()
+	vcf := filepath.Join(dir, "cards.vcf")
+	roster := filepath.Join(dir, "roster.jsonl")
+	second := strings.ReplaceAll(strings.ReplaceAll(sampleVCard, "Li Lei", "Han Meimei"), "ROLE:teacher", "ROLE:janitor")
+	os.WriteFile(vcf, []byte(sampleVCard+second), 0o644)
+
+	// The second card has an unknown role, so neither is imported.
+	if err := contactsCommand([]string{"import", vcf, roster}); err == nil {
+		t.Fatal("import with an unknown role succeeded")
+	}
+	if _, err := os.Stat(roster); !os.IsNotExist(err) {
+		t.Errorf("a failed import wrote the roster: %v", err)
+	}
+
+	os.WriteFile(vcf, []byte(sampleVCard+strings.ReplaceAll(second, "ROLE:janitor\r\n", "")), 0o644)
+	if err := contactsCommand([]string{"import", "-class", "One", vcf, roster}); err != nil {
+		t.Fatal(err)
+	}
+	r, err := OpenRoster(roster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(r.Query(Teaches(""))) + "," + names(r.Query(InClass("One"))); got != "Li Lei,Han Meimei" {
+		t.Errorf("imported teachers,students = %q, want \"Li Lei,Han Meimei\"", got)
+	}
+}
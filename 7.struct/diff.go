@@ -0,0 +1,332 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// Diff compares two values of the same type field by field and reports every
+// leaf that differs, with its path from the root:
+//
+//	Human.Contact.Tel: "18833445566" → "19911223344"
+//
+// Slices are aligned on their longest common subsequence, so an element
+// inserted in the middle is one insertion rather than a change to every
+// element after it. Map entries are matched by key.
+
+// ChangeKind says how a leaf differs.
+type ChangeKind string
+
+const (
+	Changed ChangeKind = "changed"
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+)
+
+// Change is one difference. Old is unset for Added and New for Removed.
+// Values of unexported fields are stored in their printed form.
+type Change struct {
+	Path string      `json:"path"`
+	Kind ChangeKind  `json:"kind"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("%s: added %s", c.Path, display(c.New))
+	case Removed:
+		return fmt.Sprintf("%s: removed %s", c.Path, display(c.Old))
+	}
+	return fmt.Sprintf("%s: %s → %s", c.Path, display(c.Old), display(c.New))
+}
+
+func display(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return Compact(v)
+}
+
+// Changes is the result of Diff. It prints one change per line and
+// marshals to a JSON array.
+type Changes []Change
+
+func (cs Changes) String() string {
+	lines := make([]string, len(cs))
+	for i, c := range cs {
+		lines[i] = c.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// JSON returns the changes as indented JSON.
+func (cs Changes) JSON() ([]byte, error) {
+	if cs == nil {
+		cs = Changes{}
+	}
+	return json.MarshalIndent(cs, "", "  ")
+}
+
+// A DiffOption changes how Diff compares values.
+type DiffOption func(*differ)
+
+// IgnoreFields skips the given fields. A name with a dot is a full path such
+// as "Human.Contact.Tel"; a plain name such as "Age" matches that field
+// anywhere.
+func IgnoreFields(names ...string) DiffOption {
+	return func(d *differ) {
+		for _, n := range names {
+			d.ignore[n] = true
+		}
+	}
+}
+
+// FloatTolerance treats floats as equal when they differ by at most tol.
+func FloatTolerance(tol float64) DiffOption {
+	return func(d *differ) { d.tol = tol }
+}
+
+type differ struct {
+	ignore  map[string]bool
+	tol     float64
+	changes Changes
+	// seen holds the pairs of pointers and maps on the path from the root.
+	// Meeting a pair again means both values are cycles that have matched so
+	// far, so the pair is treated as equal instead of compared forever.
+	seen map[visit]bool
+}
+
+type visit struct {
+	a, b uintptr
+	t    reflect.Type
+}
+
+// Diff returns the differences between a and b, or nil if there are none.
+// Values of different types are reported as one change at the root.
+func Diff(a, b interface{}, opts ...DiffOption) Changes {
+	d := &differ{ignore: make(map[string]bool), seen: make(map[visit]bool)}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.diff("", reflect.ValueOf(a), reflect.ValueOf(b))
+	return d.changes
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func (d *differ) add(path string, kind ChangeKind, a, b reflect.Value) {
+	if path == "" {
+		path = "."
+	}
+	d.changes = append(d.changes, Change{Path: path, Kind: kind, Old: value(a), New: value(b)})
+}
+
+// value returns the value held by v, or its printed form if v was read
+// through an unexported field.
+func value(v reflect.Value) interface{} {
+	switch {
+	case !v.IsValid():
+		return nil
+	case v.CanInterface():
+		return v.Interface()
+	}
+	p := &prettyPrinter{seen: make(map[prettyVisit]bool)}
+	p.compact(v, 1)
+	return p.b.String()
+}
+
+func (d *differ) diff(path string, a, b reflect.Value) {
+	if !a.IsValid() || !b.IsValid() {
+		if a.IsValid() != b.IsValid() {
+			d.add(path, Changed, a, b)
+		}
+		return
+	}
+	if a.Type() != b.Type() {
+		d.add(path, Changed, a, b)
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Struct:
+		t := a.Type()
+		for i := 0; i < t.NumField(); i++ {
+			name := t.Field(i).Name
+			fpath := join(path, name)
+			if d.ignore[name] || d.ignore[fpath] {
+				continue
+			}
+			d.diff(fpath, a.Field(i), b.Field(i))
+		}
+	case reflect.Pointer, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				d.add(path, Changed, a, b)
+			}
+			return
+		}
+		if a.Kind() == reflect.Pointer {
+			if a.Pointer() == b.Pointer() {
+				return
+			}
+			if !d.enter(a, b) {
+				return
+			}
+			defer d.leave(a, b)
+		}
+		d.diff(path, a.Elem(), b.Elem())
+	case reflect.Slice, reflect.Array:
+		d.diffSlice(path, a, b)
+	case reflect.Map:
+		if !d.enter(a, b) {
+			return
+		}
+		defer d.leave(a, b)
+		d.diffMap(path, a, b)
+	case reflect.Float32, reflect.Float64:
+		x, y := a.Float(), b.Float()
+		if x != y && !(math.Abs(x-y) <= d.tol) && !(math.IsNaN(x) && math.IsNaN(y)) {
+			d.add(path, Changed, a, b)
+		}
+	default:
+		if scalar(a) != scalar(b) {
+			d.add(path, Changed, a, b)
+		}
+	}
+}
+
+// enter records the pointers or maps a and b on the path and reports
+// whether they were not on it already.
+func (d *differ) enter(a, b reflect.Value) bool {
+	v := visit{a.Pointer(), b.Pointer(), a.Type()}
+	if d.seen[v] {
+		return false
+	}
+	d.seen[v] = true
+	return true
+}
+
+func (d *differ) leave(a, b reflect.Value) {
+	delete(d.seen, visit{a.Pointer(), b.Pointer(), a.Type()})
+}
+
+// equal reports whether a and b have no differences under d's options.
+func (d *differ) equal(a, b reflect.Value) bool {
+	sub := &differ{ignore: d.ignore, tol: d.tol, seen: d.seen}
+	sub.diff("", a, b)
+	return len(sub.changes) == 0
+}
+
+func (d *differ) diffSlice(path string, a, b reflect.Value) {
+	n, m := a.Len(), b.Len()
+	// lcs[i][j] is the length of the longest common subsequence
+	// of a[i:] and b[j:].
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if d.equal(a.Index(i), b.Index(j)) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// Walk the table. Between two matched elements, removed and added
+	// elements are paired up and compared as changes; the rest are reported
+	// as removals (with indexes into a) and additions (indexes into b).
+	i, j := 0, 0
+	var gapA, gapB []int
+	flush := func() {
+		k := 0
+		for ; k < len(gapA) && k < len(gapB); k++ {
+			d.diff(fmt.Sprintf("%s[%d]", path, gapB[k]), a.Index(gapA[k]), b.Index(gapB[k]))
+		}
+		for _, x := range gapA[k:] {
+			d.add(fmt.Sprintf("%s[%d]", path, x), Removed, a.Index(x), reflect.Value{})
+		}
+		for _, y := range gapB[k:] {
+			d.add(fmt.Sprintf("%s[%d]", path, y), Added, reflect.Value{}, b.Index(y))
+		}
+		gapA, gapB = gapA[:0], gapB[:0]
+	}
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && d.equal(a.Index(i), b.Index(j)):
+			flush()
+			i++
+			j++
+		case j >= m || i < n && lcs[i+1][j] >= lcs[i][j+1]:
+			gapA = append(gapA, i)
+			i++
+		default:
+			gapB = append(gapB, j)
+			j++
+		}
+	}
+	flush()
+}
+
+func (d *differ) diffMap(path string, a, b reflect.Value) {
+	for _, k := range sortedKeys(a) {
+		kpath := fmt.Sprintf("%s[%s]", path, scalar(k))
+		bv := b.MapIndex(k)
+		if !bv.IsValid() {
+			d.add(kpath, Removed, a.MapIndex(k), reflect.Value{})
+			continue
+		}
+		d.diff(kpath, a.MapIndex(k), bv)
+	}
+	for _, k := range sortedKeys(b) {
+		if !a.MapIndex(k).IsValid() {
+			d.add(fmt.Sprintf("%s[%s]", path, scalar(k)), Added, reflect.Value{}, b.MapIndex(k))
+		}
+	}
+}
+
+func diffMain(t Teacher) {
+	t2 := t
+	t2.Age = 31
+	t2.Contact.Tel = "19911223344"
+	fmt.Println(Diff(t, t2))
+	// Human.Age: 30 → 31
+	// Human.Contact.Tel: "18833445566" → "19911223344"
+
+	fmt.Println(Diff(t, t2, IgnoreFields("Age", "Human.Contact.Tel")) == nil) // true
+
+	before := map[string][]float64{"scores": {90, 85.5, 70}}
+	after := map[string][]float64{"scores": {90, 80, 85.50001, 70}, "rank": {1}}
+	changes := Diff(before, after, FloatTolerance(1e-3))
+	fmt.Println(changes)
+	// ["scores"][1]: added 80
+	// ["rank"]: added [1]
+	js, _ := changes.JSON()
+	fmt.Println(string(js))
+	// [
+	//   {
+	//     "path": "[\"scores\"][1]",
+	//     "kind": "added",
+	//     "new": 80
+	//   },
+	//   {
+	//     "path": "[\"rank\"]",
+	//     "kind": "added",
+	//     "new": [
+	//       1
+	//     ]
+	//   }
+	// ]
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDiffStruct(t *testing.T) {
+	var a Teacher
+	a.Name, a.Gender, a.Age, a.Subject = "Tom", "male", 30, "CS"
+	a.Contact.Address, a.Contact.Tel = "Beijing", "18833445566"
+	b := a
+	b.Age = 31
+	b.Contact.Tel = "19911223344"
+
+	got := Diff(a, b)
+	want := Changes{
+		{Path: "Human.Age", Kind: Changed, Old: 30, New: 31},
+		{Path: "Human.Contact.Tel", Kind: Changed, Old: "18833445566", New: "19911223344"},
+	}
+	if got.String() != want.String() {
+		t.Errorf("Diff =\n%v\nwant\n%v", got, want)
+	}
+	if got := Diff(a, b, IgnoreFields("Age", "Human.Contact.Tel")); got != nil {
+		t.Errorf("Diff with ignored fields = %v, want nil", got)
+	}
+	if got := Diff(a, a); got != nil {
+		t.Errorf("Diff(a, a) = %v, want nil", got)
+	}
+}
+
+func TestDiffSliceAndMap(t *testing.T) {
+	tests := []struct {
+		a, b interface{}
+		opts []DiffOption
+		want string
+	}{
+		{[]int{1, 2, 3}, []int{1, 9, 2, 3}, nil, "[1]: added 9"},
+		{[]int{1, 2, 3}, []int{1, 3}, nil, "[1]: removed 2"},
+		{[]int{1, 2, 3}, []int{1, 5, 3}, nil, "[1]: 2 → 5"},
+		{[]float64{1.5}, []float64{1.5001}, []DiffOption{FloatTolerance(1e-3)}, ""},
+		{map[string]int{"a": 1, "b": 2}, map[string]int{"a": 1, "c": 3}, nil, "[\"b\"]: removed 2\n[\"c\"]: added 3"},
+		{1, "1", nil, ".: 1 → \"1\""},
+	}
+	for _, tt := range tests {
+		if got := Diff(tt.a, tt.b, tt.opts...).String(); got != tt.want {
+			t.Errorf("Diff(%v, %v) =\n%s\nwant\n%s", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDiffCycles(t *testing.T) {
+	ring := func(names ...string) *node {
+		first := &node{name: names[0]}
+		n := first
+		for _, name := range names[1:] {
+			n.next = &node{name: name}
+			n = n.next
+		}
+		n.next = first
+		return first
+	}
+
+	if got := Diff(ring("a", "b"), ring("a", "b")); got != nil {
+		t.Errorf("Diff of equal rings = %v, want nil", got)
+	}
+	got := Diff(ring("a", "b"), ring("a", "c"))
+	if len(got) != 1 || got[0].Path != "next.name" {
+		t.Errorf("Diff of different rings = %v, want one change at next.name", got)
+	}
+
+	m1 := map[string]interface{}{"n": 1}
+	m1["self"] = m1
+	m2 := map[string]interface{}{"n": 2}
+	m2["self"] = m2
+	got = Diff(m1, m2)
+	if len(got) != 1 || got[0].Path != `["n"]` {
+		t.Errorf("Diff of self-referencing maps = %v, want one change at [\"n\"]", got)
+	}
+}
+
+func TestChangesJSON(t *testing.T) {
+	js, err := Changes(nil).JSON()
+	if err != nil || string(js) != "[]" {
+		t.Errorf("Changes(nil).JSON() = %s, %v; want [], nil", js, err)
+	}
+
+	js, err = Diff([]int{1}, []int{1, 2}).JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []map[string]interface{}
+	if err := json.Unmarshal(js, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0]["kind"] != "added" || got[0]["new"] != 2.0 {
+		t.Errorf("JSON = %s, want one added change with new 2", js)
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A field declared with a type but no name is an embedded field. The fields
+// and methods of an embedded type are promoted: t.Name can be written for
+// t.Human.Name. A selector picks the shallowest field or method with its name;
+// one at a greater depth is shadowed, and two at the same shallowest depth are
+// ambiguous, which is a compile error only when the selector is used.
+// A named field such as SubType.Super promotes nothing.
+
+// embedding lists, for each named type of a package, every field and method
+// reachable through embedding with its depth, flags shadowed and ambiguous
+// names, and compares the method sets of T and *T.
+//
+// Usage:
+//
+//	go run 7.struct/embedding/embedding.go [-type Teacher] 7.struct
+func main() {
+	only := flag.String("type", "", "only explore this type")
+	flag.Parse()
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	pkg, err := load(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "embedding:", err)
+		os.Exit(1)
+	}
+	scope := pkg.Scope()
+	found := false
+	for _, name := range scope.Names() {
+		if *only != "" && name != *only {
+			continue
+		}
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || tn.IsAlias() {
+			continue
+		}
+		named, ok := tn.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+		found = true
+		explore(os.Stdout, named)
+	}
+	if !found {
+		fmt.Fprintln(os.Stderr, "embedding: no such type", *only)
+		os.Exit(1)
+	}
+}
+
+func load(dir string) (*types.Package, error) {
+	fset := token.NewFileSet()
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	var parsed []*ast.File
+	for _, f := range files {
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, f, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, file)
+	}
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	return conf.Check(parsed[0].Name.Name, fset, parsed, nil)
+}
+
+// A member is a field or method found at some depth below the root type.
+type member struct {
+	name   string
+	kind   string // "field" or "method"
+	typ    string
+	depth  int
+	path   []string // embedded fields leading to the member
+	status string   // "", "shadowed" or "ambiguous"
+}
+
+func (m *member) selector() string {
+	return strings.Join(append(append([]string(nil), m.path...), m.name), ".")
+}
+
+func qualifier(*types.Package) string { return "" }
+
+// typeString keeps each member on one line by writing struct{...} for an
+// anonymous struct.
+func typeString(t types.Type) string {
+	if _, ok := t.(*types.Struct); ok {
+		return "struct{...}"
+	}
+	return types.TypeString(t, qualifier)
+}
+
+// members walks the embedded fields breadth first, as the spec describes
+// selector resolution, and returns everything reachable from t. A type seen
+// at a shallower depth is walked only there, since everything it promotes is
+// shadowed below; a type reached twice at the same depth is walked twice, so
+// its members are counted as ambiguous, as in types.LookupFieldOrMethod.
+func members(t types.Type) []*member {
+	type entry struct {
+		typ  types.Type
+		path []string
+	}
+	var all []*member
+	current := []entry{{t, nil}}
+	seen := make(map[*types.Named]int) // depth at which each type was walked
+	for depth := 0; len(current) > 0; depth++ {
+		var next []entry
+		for _, e := range current {
+			typ := e.typ
+			if p, ok := typ.(*types.Pointer); ok {
+				typ = p.Elem()
+			}
+			if named, ok := typ.(*types.Named); ok {
+				if d, ok := seen[named]; ok && d < depth {
+					continue
+				}
+				seen[named] = depth
+				for i := 0; i < named.NumMethods(); i++ {
+					m := named.Method(i)
+					all = append(all, &member{name: m.Name(), kind: "method",
+						typ: types.TypeString(m.Type(), qualifier), depth: depth, path: e.path})
+				}
+			}
+			var st *types.Struct
+			switch u := typ.Underlying().(type) {
+			case *types.Interface:
+				// An interface's methods, including those of the interfaces
+				// it embeds, belong to the interface type, not the named one.
+				for i := 0; i < u.NumMethods(); i++ {
+					m := u.Method(i)
+					all = append(all, &member{name: m.Name(), kind: "method",
+						typ: types.TypeString(m.Type(), qualifier), depth: depth, path: e.path})
+				}
+				continue
+			case *types.Struct:
+				st = u
+			default:
+				continue
+			}
+			for i := 0; i < st.NumFields(); i++ {
+				f := st.Field(i)
+				all = append(all, &member{name: f.Name(), kind: "field",
+					typ: typeString(f.Type()), depth: depth, path: e.path})
+				if f.Embedded() {
+					path := append(append([]string(nil), e.path...), f.Name())
+					next = append(next, entry{f.Type(), path})
+				}
+			}
+		}
+		current = next
+	}
+
+	// Mark the members that a selector with their name cannot reach.
+	byName := make(map[string][]*member)
+	for _, m := range all {
+		byName[m.name] = append(byName[m.name], m)
+	}
+	for _, ms := range byName {
+		shallowest := ms[0].depth
+		count := 0
+		for _, m := range ms {
+			if m.depth == shallowest {
+				count++
+			}
+		}
+		for _, m := range ms {
+			switch {
+			case m.depth > shallowest:
+				m.status = "shadowed"
+			case count > 1:
+				m.status = "ambiguous"
+			}
+		}
+	}
+	return all
+}
+
+func explore(w io.Writer, named *types.Named) {
+	fmt.Fprintf(w, "type %s %s\n", named.Obj().Name(), kindOf(named))
+	ms := members(named)
+	sort.SliceStable(ms, func(i, j int) bool { return ms[i].depth < ms[j].depth })
+	for _, m := range ms {
+		if m.depth == 0 && m.kind == "field" {
+			note := ""
+			if embedded(named, m.name) {
+				note = "  (embedded)"
+			} else if fieldIsStruct(named, m.name) {
+				note = "  (named field: its fields are not promoted)"
+			}
+			fmt.Fprintf(w, "  %-28s field  %-22s depth 0%s\n", m.selector(), m.typ, note)
+			continue
+		}
+		line := fmt.Sprintf("  %-28s %-6s %-22s depth %d", m.selector(), m.kind, m.typ, m.depth)
+		if m.depth > 0 {
+			line += "  promoted as ." + m.name
+		}
+		switch m.status {
+		case "shadowed":
+			line = fmt.Sprintf("  %-28s %-6s %-22s depth %d  SHADOWED by a shallower %s",
+				m.selector(), m.kind, m.typ, m.depth, m.name)
+		case "ambiguous":
+			line = fmt.Sprintf("  %-28s %-6s %-22s depth %d  AMBIGUOUS: .%s is an error",
+				m.selector(), m.kind, m.typ, m.depth, m.name)
+		}
+		fmt.Fprintln(w, line)
+	}
+
+	// The method set of T holds the methods with value receivers, including
+	// promoted ones; *T adds those with pointer receivers. Embedding *E in T
+	// puts E's pointer methods in T's method set too.
+	valueSet := types.NewMethodSet(named)
+	ptrSet := types.NewMethodSet(types.NewPointer(named))
+	if ptrSet.Len() > 0 {
+		fmt.Fprintf(w, "  method set of %s:", named.Obj().Name())
+		printSet(w, valueSet)
+		fmt.Fprintf(w, "  method set of *%s:", named.Obj().Name())
+		printSet(w, ptrSet)
+		var onlyPtr []string
+		for i := 0; i < ptrSet.Len(); i++ {
+			sel := ptrSet.At(i)
+			if valueSet.Lookup(sel.Obj().Pkg(), sel.Obj().Name()) == nil {
+				onlyPtr = append(onlyPtr, sel.Obj().Name())
+			}
+		}
+		if len(onlyPtr) > 0 {
+			fmt.Fprintf(w, "  only *%s has: %s (a %s value does not satisfy interfaces needing them)\n",
+				named.Obj().Name(), strings.Join(onlyPtr, ", "), named.Obj().Name())
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+func printSet(w io.Writer, ms *types.MethodSet) {
+	if ms.Len() == 0 {
+		fmt.Fprintln(w, " (empty)")
+		return
+	}
+	for i := 0; i < ms.Len(); i++ {
+		sel := ms.At(i)
+		depth := len(sel.Index()) - 1
+		fmt.Fprintf(w, " %s", sel.Obj().Name())
+		if depth > 0 {
+			fmt.Fprintf(w, "(depth %d)", depth)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+func deref(t types.Type) types.Type {
+	if p, ok := t.(*types.Pointer); ok {
+		return p.Elem()
+	}
+	return t
+}
+
+func kindOf(t types.Type) string {
+	switch t.Underlying().(type) {
+	case *types.Struct:
+		return "struct"
+	case *types.Interface:
+		return "interface"
+	}
+	return types.TypeString(t.Underlying(), qualifier)
+}
+
+func field(t types.Type, name string) *types.Var {
+	st, ok := deref(t).Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		if st.Field(i).Name() == name {
+			return st.Field(i)
+		}
+	}
+	return nil
+}
+
+func embedded(t types.Type, name string) bool {
+	f := field(t, name)
+	return f != nil && f.Embedded()
+}
+
+func fieldIsStruct(t types.Type, name string) bool {
+	f := field(t, name)
+	if f == nil {
+		return false
+	}
+	_, ok := deref(f.Type()).Underlying().(*types.Struct)
+	return ok
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const embeddingSource = `package fixture
+
+type Named struct{ Name string }
+
+func (Named) Hello() string { return "" }
+
+type Labelled struct{ Name string }
+
+func (*Labelled) Set(string) {}
+
+type Stringer interface{ String() string }
+
+// Both has two Name fields at depth 1: .Name is ambiguous.
+type Both struct {
+	Named
+	*Labelled
+}
+
+// Own declares Name itself, which shadows Named.Name.
+type Own struct {
+	Named
+	Name int
+	Stringer
+}
+
+// ByValue embeds Labelled, whose Set needs a pointer.
+type ByValue struct {
+	Labelled
+}
+
+// Field names a struct: nothing is promoted.
+type Field struct {
+	N Named
+}
+`
+
+func exploreFixture(t *testing.T, name string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "fixture.go"), []byte(embeddingSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	explore(&b, pkg.Scope().Lookup(name).Type().(*types.Named))
+	return b.String()
+}
+
+func TestExplore(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Both", `type Both struct
+  Named                        field  Named                  depth 0  (embedded)
+  Labelled                     field  *Labelled              depth 0  (embedded)
+  Named.Hello                  method func() string          depth 1  promoted as .Hello
+  Named.Name                   field  string                 depth 1  AMBIGUOUS: .Name is an error
+  Labelled.Set                 method func(string)           depth 1  promoted as .Set
+  Labelled.Name                field  string                 depth 1  AMBIGUOUS: .Name is an error
+  method set of Both: Hello(depth 1) Set(depth 1)
+  method set of *Both: Hello(depth 1) Set(depth 1)
+
+`},
+		{"Own", `type Own struct
+  Named                        field  Named                  depth 0  (embedded)
+  Name                         field  int                    depth 0
+  Stringer                     field  Stringer               depth 0  (embedded)
+  Named.Hello                  method func() string          depth 1  promoted as .Hello
+  Named.Name                   field  string                 depth 1  SHADOWED by a shallower Name
+  Stringer.String              method func() string          depth 1  promoted as .String
+  method set of Own: Hello(depth 1) String(depth 1)
+  method set of *Own: Hello(depth 1) String(depth 1)
+
+`},
+		{"ByValue", `type ByValue struct
+  Labelled                     field  Labelled               depth 0  (embedded)
+  Labelled.Set                 method func(string)           depth 1  promoted as .Set
+  Labelled.Name                field  string                 depth 1  promoted as .Name
+  method set of ByValue: (empty)
+  method set of *ByValue: Set(depth 1)
+  only *ByValue has: Set (a ByValue value does not satisfy interfaces needing them)
+
+`},
+		{"Field", `type Field struct
+  N                            field  Named                  depth 0  (named field: its fields are not promoted)
+
+`},
+	}
+	for _, tt := range tests {
+		if got := exploreFixture(t, tt.name); got != tt.want {
+			t.Errorf("%s:\n%s\nwant:\n%s", tt.name, got, tt.want)
+		}
+	}
+}
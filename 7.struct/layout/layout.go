@@ -0,0 +1,186 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// The fields of a struct are laid out in memory in declaration order, each at
+// an offset that is a multiple of its alignment. The compiler inserts padding
+// to get there, and more padding at the end so that the size is a multiple
+// of the struct's alignment. Ordering fields from the largest alignment to the
+// smallest removes most of that padding.
+
+// layout type-checks a package and prints the memory layout of each of its
+// struct types, with a field order that needs less padding when there is one.
+//
+// Usage:
+//
+//	go run 7.struct/layout/layout.go [-arch amd64] [-cacheline 64] 7.struct
+func main() {
+	arch := flag.String("arch", runtime.GOARCH, "target architecture for sizes")
+	cacheLine := flag.Int64("cacheline", 64, "warn about structs larger than this many bytes")
+	flag.Parse()
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	sizes := types.SizesFor("gc", *arch)
+	if sizes == nil {
+		fmt.Fprintln(os.Stderr, "layout: unknown architecture", *arch)
+		os.Exit(2)
+	}
+	pkg, err := load(dir, sizes)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "layout:", err)
+		os.Exit(1)
+	}
+
+	reportPackage(os.Stdout, pkg, sizes, *cacheLine)
+}
+
+// reportPackage reports every struct type declared in pkg, in name order.
+func reportPackage(w io.Writer, pkg *types.Package, sizes types.Sizes, cacheLine int64) {
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		st, ok := tn.Type().Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		// The layout of Matrix[T] depends on T, and go/types cannot size
+		// a struct whose fields have a type parameter's type.
+		if named, ok := tn.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+			fmt.Fprintf(w, "%s: generic, size depends on instantiation\n\n", name)
+			continue
+		}
+		report(w, name, st, sizes, cacheLine)
+	}
+}
+
+// load parses and type-checks the non-test files in dir.
+func load(dir string, sizes types.Sizes) (*types.Package, error) {
+	fset := token.NewFileSet()
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	var parsed []*ast.File
+	for _, f := range files {
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, f, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, file)
+	}
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Sizes:    sizes,
+	}
+	return conf.Check(parsed[0].Name.Name, fset, parsed, nil)
+}
+
+func report(w io.Writer, name string, st *types.Struct, sizes types.Sizes, cacheLine int64) {
+	fields := make([]*types.Var, st.NumFields())
+	for i := range fields {
+		fields[i] = st.Field(i)
+	}
+	size, align := sizes.Sizeof(st), sizes.Alignof(st)
+	padding := totalPadding(fields, sizes)
+
+	fmt.Fprintf(w, "%s: size %d, align %d, padding %d\n", name, size, align, padding)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "  field\ttype\toffset\tsize\talign\tpadding after")
+	offsets := sizes.Offsetsof(fields)
+	for i, f := range fields {
+		fsize := sizes.Sizeof(f.Type())
+		end := size
+		if i+1 < len(fields) {
+			end = offsets[i+1]
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%d\t%d\t%d\t%d\n", f.Name(), typeString(f.Type()),
+			offsets[i], fsize, sizes.Alignof(f.Type()), end-offsets[i]-fsize)
+	}
+	tw.Flush()
+
+	if best := bestOrder(fields, sizes); totalPadding(best, sizes) < padding {
+		names := make([]string, len(best))
+		for i, f := range best {
+			names[i] = f.Name()
+		}
+		fmt.Fprintf(w, "  suggest: %s (size %d, padding %d)\n", strings.Join(names, ", "),
+			structSize(best, sizes), totalPadding(best, sizes))
+	}
+	if size > cacheLine {
+		fmt.Fprintf(w, "  warning: %d bytes is larger than a %d-byte cache line\n", size, cacheLine)
+	}
+	fmt.Fprintln(w)
+
+	// Anonymous structs, such as Human.Contact, are reported after their parent.
+	for _, f := range fields {
+		if inner, ok := f.Type().(*types.Struct); ok && inner.NumFields() > 0 {
+			report(w, name+"."+f.Name(), inner, sizes, cacheLine)
+		}
+	}
+}
+
+func typeString(t types.Type) string {
+	if st, ok := t.(*types.Struct); ok && st.NumFields() > 0 {
+		return "struct{...}"
+	}
+	return types.TypeString(t, func(*types.Package) string { return "" })
+}
+
+func structSize(fields []*types.Var, sizes types.Sizes) int64 {
+	return sizes.Sizeof(types.NewStruct(fields, nil))
+}
+
+// totalPadding is the size of the struct minus the sizes of its fields.
+func totalPadding(fields []*types.Var, sizes types.Sizes) int64 {
+	n := structSize(fields, sizes)
+	for _, f := range fields {
+		n -= sizes.Sizeof(f.Type())
+	}
+	return n
+}
+
+// bestOrder sorts fields by decreasing alignment, then decreasing size.
+// Zero-sized fields go first: a zero-sized final field gets padded so that
+// a pointer to it cannot point past the end of the struct.
+func bestOrder(fields []*types.Var, sizes types.Sizes) []*types.Var {
+	best := append([]*types.Var(nil), fields...)
+	sort.SliceStable(best, func(i, j int) bool {
+		si, sj := sizes.Sizeof(best[i].Type()), sizes.Sizeof(best[j].Type())
+		if (si == 0) != (sj == 0) {
+			return si == 0
+		}
+		ai, aj := sizes.Alignof(best[i].Type()), sizes.Alignof(best[j].Type())
+		if ai != aj {
+			return ai > aj
+		}
+		return si > sj
+	})
+	return best
+}
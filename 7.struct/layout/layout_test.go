@@ -0,0 +1,94 @@
+package main
+
+import (
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const layoutSource = `package fixture
+
+type Padded struct {
+	A bool
+	B int64
+	C bool
+	D int32
+}
+
+type Tight struct {
+	B int64
+	D int32
+	A bool
+}
+
+type Empty struct {
+	X int32
+	Z struct{}
+}
+
+type Nested struct {
+	Inner struct {
+		A byte
+		B int16
+	}
+	Big [80]byte
+}
+
+type Pair[T any] struct {
+	A, B T
+}
+`
+
+const layoutReport = `Empty: size 8, align 4, padding 4
+  field  type      offset  size  align  padding after
+  X      int32     0       4     4      0
+  Z      struct{}  4       0     1      4
+  suggest: Z, X (size 4, padding 0)
+
+Nested: size 84, align 2, padding 0
+  field  type         offset  size  align  padding after
+  Inner  struct{...}  0       4     2      0
+  Big    [80]byte     4       80    1      0
+  warning: 84 bytes is larger than a 64-byte cache line
+
+Nested.Inner: size 4, align 2, padding 1
+  field  type   offset  size  align  padding after
+  A      byte   0       1     1      1
+  B      int16  2       2     2      0
+
+Padded: size 24, align 8, padding 10
+  field  type   offset  size  align  padding after
+  A      bool   0       1     1      7
+  B      int64  8       8     8      0
+  C      bool   16      1     1      3
+  D      int32  20      4     4      0
+  suggest: B, D, A, C (size 16, padding 2)
+
+Pair: generic, size depends on instantiation
+
+Tight: size 16, align 8, padding 3
+  field  type   offset  size  align  padding after
+  B      int64  0       8     8      0
+  D      int32  8       4     4      0
+  A      bool   12      1     1      3
+
+`
+
+func TestLayout(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "fixture.go"), []byte(layoutSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sizes := types.SizesFor("gc", "amd64")
+	pkg, err := load(dir, sizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	reportPackage(&b, pkg, sizes, 64)
+	if got := b.String(); got != layoutReport {
+		t.Errorf("report:\n%s\nwant:\n%s", got, layoutReport)
+	}
+}
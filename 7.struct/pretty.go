@@ -0,0 +1,347 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// fmt.Println prints a struct as {{Tom male 30 {Beijing 18833445566}} CS},
+// without any field names. Pretty walks the value with reflection instead and
+// prints one field per line, with its name and type, indented by depth:
+//
+//	main.Teacher
+//	  Human main.Human (embedded)
+//	    Name string = "Tom"
+//	    ...
+//	  Subject string = "CS"
+//
+// Unexported fields are printed too, since reflection can still read them.
+// A pointer that leads back to a value being printed is shown as <cycle>
+// instead of being followed forever.
+
+// PrettyOptions control PrettyWith and CompactWith.
+type PrettyOptions struct {
+	MaxDepth int    // stop descending below this depth; 0 means no limit
+	Indent   string // per level, two spaces if empty; unused by CompactWith
+}
+
+// Pretty returns v as an indented tree of labelled fields.
+func Pretty(v interface{}) string {
+	return PrettyWith(v, PrettyOptions{})
+}
+
+// PrettyWith is Pretty with options.
+func PrettyWith(v interface{}, opts PrettyOptions) string {
+	if opts.Indent == "" {
+		opts.Indent = "  "
+	}
+	p := &prettyPrinter{opts: opts, seen: make(map[prettyVisit]bool)}
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return "<nil>"
+	}
+	p.b.WriteString(typeName(rv.Type()))
+	p.tree(rv, 1)
+	return p.b.String()
+}
+
+// Compact returns v on one line, with field names, like
+// main.Teacher{Human: main.Human{Name: "Tom", ...}, Subject: "CS"}.
+func Compact(v interface{}) string {
+	return CompactWith(v, PrettyOptions{})
+}
+
+// CompactWith is Compact with options.
+func CompactWith(v interface{}, opts PrettyOptions) string {
+	p := &prettyPrinter{opts: opts, seen: make(map[prettyVisit]bool)}
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return "<nil>"
+	}
+	p.compact(rv, 1)
+	return p.b.String()
+}
+
+type prettyPrinter struct {
+	b    strings.Builder
+	opts PrettyOptions
+	// seen holds the pointers and maps on the path from the root,
+	// so a cycle is reported where it closes.
+	seen map[prettyVisit]bool
+}
+
+// A prettyVisit is a pointer or map on the path. The type is part of it: a
+// pointer to a struct and one to its first field share an address.
+type prettyVisit struct {
+	ptr uintptr
+	t   reflect.Type
+}
+
+// typeName shortens anonymous struct types, which would otherwise be
+// printed with all their fields and tags.
+func typeName(t reflect.Type) string {
+	switch {
+	case t.Name() != "":
+		return t.String()
+	case t.Kind() == reflect.Struct:
+		return "struct{...}"
+	case t.Kind() == reflect.Pointer:
+		return "*" + typeName(t.Elem())
+	case t.Kind() == reflect.Slice:
+		return "[]" + typeName(t.Elem())
+	}
+	return t.String()
+}
+
+func (p *prettyPrinter) line(depth int, label string, t reflect.Type, note string) {
+	p.b.WriteString("\n")
+	p.b.WriteString(strings.Repeat(p.opts.Indent, depth))
+	p.b.WriteString(label)
+	p.b.WriteString(" ")
+	p.b.WriteString(typeName(t))
+	p.b.WriteString(note)
+}
+
+// tree prints the children of v, if any, at the given depth. The line for
+// v itself has already been written; scalars are appended to it.
+func (p *prettyPrinter) tree(v reflect.Value, depth int) {
+	if p.opts.MaxDepth > 0 && depth > p.opts.MaxDepth && hasChildren(v) {
+		p.b.WriteString(" {...}")
+		return
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			note := ""
+			if f.Anonymous {
+				note = " (embedded)"
+			}
+			if !f.IsExported() {
+				note += " (unexported)"
+			}
+			p.line(depth, f.Name, f.Type, note)
+			p.tree(v.Field(i), depth+1)
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			p.b.WriteString(" = nil")
+			return
+		}
+		p.b.WriteString(fmt.Sprintf(" (len %d)", v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			e := v.Index(i)
+			p.line(depth, fmt.Sprintf("[%d]", i), e.Type(), "")
+			p.tree(e, depth+1)
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			p.b.WriteString(" = nil")
+			return
+		}
+		if p.enter(v) {
+			p.b.WriteString(" = <cycle>")
+			return
+		}
+		defer p.leave(v)
+		p.b.WriteString(fmt.Sprintf(" (len %d)", v.Len()))
+		for _, k := range sortedKeys(v) {
+			e := v.MapIndex(k)
+			p.line(depth, "["+scalar(k)+"]", e.Type(), "")
+			p.tree(e, depth+1)
+		}
+	case reflect.Pointer:
+		if v.IsNil() {
+			p.b.WriteString(" = nil")
+			return
+		}
+		if p.enter(v) {
+			p.b.WriteString(" = <cycle>")
+			return
+		}
+		defer p.leave(v)
+		p.tree(v.Elem(), depth)
+	case reflect.Interface:
+		if v.IsNil() {
+			p.b.WriteString(" = nil")
+			return
+		}
+		p.b.WriteString(" = " + typeName(v.Elem().Type()))
+		p.tree(v.Elem(), depth)
+	default:
+		p.b.WriteString(" = " + scalar(v))
+	}
+}
+
+func (p *prettyPrinter) compact(v reflect.Value, depth int) {
+	if p.opts.MaxDepth > 0 && depth > p.opts.MaxDepth && hasChildren(v) {
+		p.b.WriteString("{...}")
+		return
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		p.b.WriteString(typeName(t) + "{")
+		for i := 0; i < t.NumField(); i++ {
+			if i > 0 {
+				p.b.WriteString(", ")
+			}
+			p.b.WriteString(t.Field(i).Name + ": ")
+			p.compact(v.Field(i), depth+1)
+		}
+		p.b.WriteString("}")
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			p.b.WriteString("nil")
+			return
+		}
+		p.b.WriteString("[")
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				p.b.WriteString(", ")
+			}
+			p.compact(v.Index(i), depth+1)
+		}
+		p.b.WriteString("]")
+	case reflect.Map:
+		if v.IsNil() {
+			p.b.WriteString("nil")
+			return
+		}
+		if p.enter(v) {
+			p.b.WriteString("<cycle>")
+			return
+		}
+		defer p.leave(v)
+		p.b.WriteString("map[")
+		for i, k := range sortedKeys(v) {
+			if i > 0 {
+				p.b.WriteString(", ")
+			}
+			p.b.WriteString(scalar(k) + ": ")
+			p.compact(v.MapIndex(k), depth+1)
+		}
+		p.b.WriteString("]")
+	case reflect.Pointer:
+		if v.IsNil() {
+			p.b.WriteString("nil")
+			return
+		}
+		if p.enter(v) {
+			p.b.WriteString("<cycle>")
+			return
+		}
+		defer p.leave(v)
+		p.b.WriteString("&")
+		p.compact(v.Elem(), depth)
+	case reflect.Interface:
+		if v.IsNil() {
+			p.b.WriteString("nil")
+			return
+		}
+		p.compact(v.Elem(), depth)
+	default:
+		p.b.WriteString(scalar(v))
+	}
+}
+
+func (p *prettyPrinter) enter(v reflect.Value) (cycle bool) {
+	k := prettyVisit{v.Pointer(), v.Type()}
+	if p.seen[k] {
+		return true
+	}
+	p.seen[k] = true
+	return false
+}
+
+func (p *prettyPrinter) leave(v reflect.Value) {
+	delete(p.seen, prettyVisit{v.Pointer(), v.Type()})
+}
+
+func hasChildren(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Struct, reflect.Array:
+		return true
+	case reflect.Slice, reflect.Map:
+		return !v.IsNil()
+	case reflect.Pointer, reflect.Interface:
+		return !v.IsNil() && hasChildren(v.Elem())
+	}
+	return false
+}
+
+// scalar formats a value that has no children. It uses the reflect
+// accessors rather than Interface, which panics on unexported fields.
+func scalar(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return strconv.Quote(v.String())
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	case reflect.Complex64, reflect.Complex128:
+		return strconv.FormatComplex(v.Complex(), 'g', -1, 128)
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		if v.IsNil() {
+			return "nil"
+		}
+		return fmt.Sprintf("%#x", v.Pointer())
+	}
+	return fmt.Sprintf("<%s>", v.Type())
+}
+
+// sortedKeys returns the keys of a map in a stable order.
+func sortedKeys(m reflect.Value) []reflect.Value {
+	keys := m.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		switch a.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return a.Int() < b.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return a.Uint() < b.Uint()
+		case reflect.Float32, reflect.Float64:
+			return a.Float() < b.Float()
+		}
+		return scalar(a) < scalar(b)
+	})
+	return keys
+}
+
+// node is a small linked structure used to show how cycles are printed.
+type node struct {
+	name string
+	next *node
+}
+
+func prettyMain(t Teacher) {
+	fmt.Println(Pretty(t))
+	// main.Teacher
+	//   Human main.Human (embedded)
+	//     Name string = "Tom"
+	//     Gender string = "male"
+	//     Age int = 30
+	//     Contact struct{...}
+	//       Address string = "Beijing"
+	//       Tel string = "18833445566"
+	//   Subject string = "CS"
+
+	fmt.Println(PrettyWith(t, PrettyOptions{MaxDepth: 1}))
+	// main.Teacher
+	//   Human main.Human (embedded) {...}
+	//   Subject string = "CS"
+
+	a := &node{name: "a"}
+	a.next = &node{name: "b", next: a}
+	fmt.Println(Compact(a))
+	// &main.node{name: "a", next: &main.node{name: "b", next: <cycle>}}
+}
@@ -0,0 +1,109 @@
+package main
+
+import "testing"
+
+type prettyPair struct {
+	First  prettyInner
+	Second *prettyInner
+}
+
+type prettyInner struct {
+	N    int
+	Tags []string
+	Ref  map[string]int
+}
+
+func TestPretty(t *testing.T) {
+	in := prettyInner{N: 1, Tags: []string{"x"}}
+	v := prettyPair{First: in, Second: &in}
+	want := `main.prettyPair
+  First main.prettyInner
+    N int = 1
+    Tags []string (len 1)
+      [0] string = "x"
+    Ref map[string]int = nil
+  Second *main.prettyInner
+    N int = 1
+    Tags []string (len 1)
+      [0] string = "x"
+    Ref map[string]int = nil`
+	if got := Pretty(v); got != want {
+		t.Errorf("Pretty:\n%s\nwant:\n%s", got, want)
+	}
+
+	want = `main.prettyPair
+  First main.prettyInner {...}
+  Second *main.prettyInner {...}`
+	if got := PrettyWith(v, PrettyOptions{MaxDepth: 1}); got != want {
+		t.Errorf("PrettyWith MaxDepth 1:\n%s\nwant:\n%s", got, want)
+	}
+	want = `main.prettyPair
+-First main.prettyInner
+--N int = 1
+--Tags []string {...}
+--Ref map[string]int = nil
+-Second *main.prettyInner
+--N int = 1
+--Tags []string {...}
+--Ref map[string]int = nil`
+	if got := PrettyWith(v, PrettyOptions{MaxDepth: 2, Indent: "-"}); got != want {
+		t.Errorf("PrettyWith MaxDepth 2:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	in := prettyInner{N: 1, Tags: []string{"x"}, Ref: map[string]int{"b": 2, "a": 1}}
+	v := prettyPair{First: in}
+	want := `main.prettyPair{First: main.prettyInner{N: 1, Tags: ["x"], Ref: map["a": 1, "b": 2]}, Second: nil}`
+	if got := Compact(v); got != want {
+		t.Errorf("Compact:\n%s\nwant:\n%s", got, want)
+	}
+	want = `main.prettyPair{First: main.prettyInner{N: 1, Tags: {...}, Ref: {...}}, Second: nil}`
+	if got := CompactWith(v, PrettyOptions{MaxDepth: 2}); got != want {
+		t.Errorf("CompactWith MaxDepth 2:\n%s\nwant:\n%s", got, want)
+	}
+	if got := Compact(nil); got != "<nil>" {
+		t.Errorf("Compact(nil) = %s", got)
+	}
+}
+
+func TestPrettyCycles(t *testing.T) {
+	a := &node{name: "a"}
+	a.next = &node{name: "b", next: a}
+	want := `&main.node{name: "a", next: &main.node{name: "b", next: <cycle>}}`
+	if got := Compact(a); got != want {
+		t.Errorf("Compact(cycle) = %s, want %s", got, want)
+	}
+	want = `*main.node
+  name string (unexported) = "a"
+  next *main.node (unexported)
+    name string (unexported) = "b"
+    next *main.node (unexported) = <cycle>`
+	if got := Pretty(a); got != want {
+		t.Errorf("Pretty(cycle):\n%s\nwant:\n%s", got, want)
+	}
+
+	m := map[string]interface{}{}
+	m["self"] = m
+	if got, want := Compact(m), `map["self": <cycle>]`; got != want {
+		t.Errorf("Compact(map cycle) = %s, want %s", got, want)
+	}
+
+	// A pointer to a struct and one to its first field share an address,
+	// but following one to the other is not a cycle.
+	type box struct {
+		inner prettyInner
+		p     *prettyInner
+	}
+	b := &box{inner: prettyInner{N: 7}}
+	b.p = &b.inner
+	want = `&main.box{inner: main.prettyInner{N: 7, Tags: nil, Ref: nil}, p: &main.prettyInner{N: 7, Tags: nil, Ref: nil}}`
+	if got := Compact(b); got != want {
+		t.Errorf("Compact(first field) =\n%s\nwant\n%s", got, want)
+	}
+	// Two pointers to one value that is not on its own path are not a cycle.
+	shared := &prettyInner{N: 3}
+	if got, want := Compact([]*prettyInner{shared, shared}), `[&main.prettyInner{N: 3, Tags: nil, Ref: nil}, &main.prettyInner{N: 3, Tags: nil, Ref: nil}]`; got != want {
+		t.Errorf("Compact(shared) = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// A Roster keeps Teacher and Student records in a JSON-lines file, one
+// record per line. Every change rewrites the file atomically: the new content
+// goes to a temporary file in the same directory, which then replaces the old
+// one, so a crash never leaves a half-written roster behind.
+
+// Record is one entry of the roster. Exactly one of Teacher and Student is set.
+type Record struct {
+	ID      int      `json:"id"`
+	Teacher *Teacher `json:"teacher,omitempty"`
+	Student *Student `json:"student,omitempty"`
+}
+
+// Human returns the Human embedded in the teacher or student.
+func (r *Record) Human() *Human {
+	switch {
+	case r.Teacher != nil:
+		return &r.Teacher.Human
+	case r.Student != nil:
+		return &r.Student.Human
+	}
+	return nil
+}
+
+// Role returns "teacher" or "student".
+func (r *Record) Role() string {
+	if r.Teacher != nil {
+		return "teacher"
+	}
+	return "student"
+}
+
+func (r *Record) check() error {
+	if (r.Teacher == nil) == (r.Student == nil) {
+		return errors.New("roster: record must be either a teacher or a student")
+	}
+	return nil
+}
+
+// ErrNotFound is returned when no record has the requested ID.
+var ErrNotFound = errors.New("roster: record not found")
+
+// Roster is a set of records backed by a file. It is safe to use concurrently.
+type Roster struct {
+	mu      sync.Mutex
+	path    string
+	records map[int]*Record
+	nextID  int
+}
+
+// OpenRoster loads the roster stored at path.
+// A missing file is an empty roster; it is created on the first change.
+func OpenRoster(path string) (*Roster, error) {
+	r := &Roster{path: path, records: make(map[int]*Record), nextID: 1}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		rec := new(Record)
+		if err := json.Unmarshal(sc.Bytes(), rec); err != nil {
+			return nil, fmt.Errorf("roster: %s:%d: %v", path, line, err)
+		}
+		if err := rec.check(); err != nil {
+			return nil, fmt.Errorf("%v (%s:%d)", err, path, line)
+		}
+		r.put(rec)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Roster) put(rec *Record) {
+	r.records[rec.ID] = rec
+	if rec.ID >= r.nextID {
+		r.nextID = rec.ID + 1
+	}
+}
+
+// sorted returns the records in ID order.
+func (r *Roster) sorted() []*Record {
+	recs := make([]*Record, 0, len(r.records))
+	for _, rec := range r.records {
+		recs = append(recs, rec)
+	}
+	sort.Slice(recs, func(i, j int) bool { return recs[i].ID < recs[j].ID })
+	return recs
+}
+
+// save writes all records to a temporary file and renames it over the roster.
+func (r *Roster) save() (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, rec := range r.sorted() {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return err
+	}
+	// The rename itself is only durable once the directory is synced.
+	return syncDir(filepath.Dir(r.path))
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// clone returns a deep copy, so callers never share a record with the roster.
+func (rec *Record) clone() *Record {
+	c := &Record{ID: rec.ID}
+	if rec.Teacher != nil {
+		t := *rec.Teacher
+		c.Teacher = &t
+	}
+	if rec.Student != nil {
+		s := *rec.Student
+		c.Student = &s
+	}
+	return c
+}
+
+// Create adds rec with a new ID and returns that ID.
+func (r *Roster) Create(rec Record) (int, error) {
+	ids, err := r.CreateAll([]Record{rec})
+	if err != nil {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// CreateAll adds the records with new IDs in a single write and returns the
+// IDs. Either every record is added or, on error, none is.
+func (r *Roster) CreateAll(recs []Record) ([]int, error) {
+	for _, rec := range recs {
+		if err := rec.check(); err != nil {
+			return nil, err
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	nextID := r.nextID
+	ids := make([]int, len(recs))
+	for i, rec := range recs {
+		rec.ID = r.nextID
+		r.put(rec.clone())
+		ids[i] = rec.ID
+	}
+	if err := r.save(); err != nil {
+		for _, id := range ids {
+			delete(r.records, id)
+		}
+		r.nextID = nextID
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Get returns a copy of the record with the given ID.
+func (r *Roster) Get(id int) (Record, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec, ok := r.records[id]
+	if !ok {
+		return Record{}, ErrNotFound
+	}
+	return *rec.clone(), nil
+}
+
+// Update replaces the record with the same ID as rec.
+func (r *Roster) Update(rec Record) error {
+	if err := rec.check(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old, ok := r.records[rec.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	r.records[rec.ID] = rec.clone()
+	if err := r.save(); err != nil {
+		r.records[rec.ID] = old
+		return err
+	}
+	return nil
+}
+
+// Delete removes the record with the given ID.
+func (r *Roster) Delete(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old, ok := r.records[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(r.records, id)
+	if err := r.save(); err != nil {
+		r.records[id] = old
+		return err
+	}
+	return nil
+}
+
+// A Filter selects records in a query.
+type Filter func(*Record) bool
+
+// AgeBetween selects people whose age is in [min, max].
+func AgeBetween(min, max int) Filter {
+	return func(r *Record) bool {
+		age := r.Human().Age
+		return age >= min && age <= max
+	}
+}
+
+// InClass selects the students of a class.
+func InClass(class string) Filter {
+	return func(r *Record) bool {
+		return r.Student != nil && r.Student.Class == class
+	}
+}
+
+// Teaches selects the teachers of a subject.
+func Teaches(subject string) Filter {
+	return func(r *Record) bool {
+		return r.Teacher != nil && r.Teacher.Subject == subject
+	}
+}
+
+// InCity selects people whose address is the given city.
+func InCity(city string) Filter {
+	return func(r *Record) bool {
+		return r.Human().Contact.Address == city
+	}
+}
+
+// Query returns copies of the records matching every filter, in ID order.
+func (r *Roster) Query(filters ...Filter) []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []Record
+next:
+	for _, rec := range r.sorted() {
+		for _, f := range filters {
+			if !f(rec) {
+				continue next
+			}
+		}
+		out = append(out, *rec.clone())
+	}
+	return out
+}
+
+var csvHeader = []string{"id", "role", "name", "gender", "age", "address", "tel", "subject", "class"}
+
+// ExportCSV writes all records as CSV with a header line.
+func (r *Roster) ExportCSV(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	for _, rec := range r.sorted() {
+		h := rec.Human()
+		row := []string{strconv.Itoa(rec.ID), rec.Role(), h.Name, h.Gender,
+			strconv.Itoa(h.Age), h.Contact.Address, h.Contact.Tel, "", ""}
+		if rec.Teacher != nil {
+			row[7] = rec.Teacher.Subject
+		} else {
+			row[8] = rec.Student.Class
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ImportCSV adds the records of a CSV file written by ExportCSV.
+// Rows keep their ID unless it is empty or already taken. Nothing is
+// imported if any row is invalid.
+func (r *Roster) ImportCSV(rd io.Reader) (int, error) {
+	cr := csv.NewReader(rd)
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	col := make(map[string]int)
+	for i, name := range rows[0] {
+		col[name] = i
+	}
+	for _, name := range csvHeader {
+		if _, ok := col[name]; !ok {
+			return 0, fmt.Errorf("roster: CSV header has no %q column", name)
+		}
+	}
+
+	var recs []*Record
+	for i, row := range rows[1:] {
+		get := func(name string) string { return row[col[name]] }
+		age, err := strconv.Atoi(get("age"))
+		if err != nil {
+			return 0, fmt.Errorf("roster: CSV line %d: bad age: %v", i+2, err)
+		}
+		var h Human
+		h.Name, h.Gender, h.Age = get("name"), get("gender"), age
+		h.Contact.Address, h.Contact.Tel = get("address"), get("tel")
+
+		rec := new(Record)
+		if id := get("id"); id != "" {
+			if rec.ID, err = strconv.Atoi(id); err != nil {
+				return 0, fmt.Errorf("roster: CSV line %d: bad id: %v", i+2, err)
+			}
+		}
+		switch get("role") {
+		case "teacher":
+			rec.Teacher = &Teacher{Human: h, Subject: get("subject")}
+		case "student":
+			rec.Student = &Student{Human: h, Class: get("class")}
+		default:
+			return 0, fmt.Errorf("roster: CSV line %d: unknown role %q", i+2, get("role"))
+		}
+		recs = append(recs, rec)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	nextID := r.nextID
+	var added []int
+	for _, rec := range recs {
+		if _, taken := r.records[rec.ID]; rec.ID <= 0 || taken {
+			rec.ID = r.nextID
+		}
+		r.put(rec)
+		added = append(added, rec.ID)
+	}
+	if err := r.save(); err != nil {
+		for _, id := range added {
+			delete(r.records, id)
+		}
+		r.nextID = nextID
+		return 0, err
+	}
+	return len(recs), nil
+}
+
+func rosterMain(t Teacher, s Student) {
+	dir, err := os.MkdirTemp("", "roster")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	roster, err := OpenRoster(filepath.Join(dir, "roster.jsonl"))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	tid, _ := roster.Create(Record{Teacher: &t})
+	sid, _ := roster.Create(Record{Student: &s})
+
+	s.Age++
+	roster.Update(Record{ID: sid, Student: &s})
+	got, _ := roster.Get(sid)
+	fmt.Println(got.ID, *got.Student) // 2 {{Daniel male 19 {Beijing 19911223344}} One}
+
+	for _, rec := range roster.Query(InCity("Beijing"), AgeBetween(20, 40)) {
+		fmt.Println(rec.ID, rec.Human().Name) // 1 Tom
+	}
+
+	// Reopening the file gives back the same records.
+	again, _ := OpenRoster(roster.path)
+	fmt.Println(len(again.Query(Teaches("CS")))) // 1
+
+	roster.Delete(tid)
+	roster.ExportCSV(os.Stdout)
+	// id,role,name,gender,age,address,tel,subject,class
+	// 2,student,Daniel,male,19,Beijing,19911223344,,One
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func human(name string, age int, city string) Human {
+	h := Human{Name: name, Gender: "female", Age: age}
+	h.Contact.Address = city
+	h.Contact.Tel = "13800000000"
+	return h
+}
+
+func openRoster(t *testing.T, path string) *Roster {
+	t.Helper()
+	r, err := OpenRoster(path)
+	if err != nil {
+		t.Fatalf("OpenRoster: %v", err)
+	}
+	return r
+}
+
+// fillRoster adds two teachers and three students.
+func fillRoster(t *testing.T, r *Roster) {
+	t.Helper()
+	recs := []Record{
+		{Teacher: &Teacher{Human: human("Ann", 45, "Beijing"), Subject: "CS"}},
+		{Teacher: &Teacher{Human: human("Bob", 30, "Shanghai"), Subject: "Math"}},
+		{Student: &Student{Human: human("Cai", 18, "Beijing"), Class: "One"}},
+		{Student: &Student{Human: human("Dan", 20, "Beijing"), Class: "Two"}},
+		{Student: &Student{Human: human("Eve", 19, "Shanghai"), Class: "One"}},
+	}
+	for i, rec := range recs {
+		if id, err := r.Create(rec); err != nil || id != i+1 {
+			t.Fatalf("Create #%d = %d, %v", i+1, id, err)
+		}
+	}
+}
+
+func names(recs []Record) string {
+	var s []string
+	for _, rec := range recs {
+		s = append(s, rec.Human().Name)
+	}
+	return strings.Join(s, " ")
+}
+
+func TestRosterCRUD(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "roster.jsonl")
+	r := openRoster(t, path)
+	fillRoster(t, r)
+
+	if _, err := r.Create(Record{}); err == nil {
+		t.Error("Create of a record with no role succeeded")
+	}
+	rec, err := r.Get(3)
+	if err != nil || rec.Student == nil || rec.Student.Name != "Cai" {
+		t.Fatalf("Get(3) = %+v, %v", rec, err)
+	}
+	// Get returns a copy.
+	rec.Student.Age = 99
+	if again, _ := r.Get(3); again.Student.Age != 18 {
+		t.Error("changing a record from Get changed the roster")
+	}
+	if err := r.Update(rec); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Update(Record{ID: 42, Student: rec.Student}); err != ErrNotFound {
+		t.Errorf("Update of a missing ID = %v, want ErrNotFound", err)
+	}
+	if err := r.Delete(2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Get(2); err != ErrNotFound {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+	if err := r.Delete(2); err != ErrNotFound {
+		t.Errorf("second Delete = %v, want ErrNotFound", err)
+	}
+
+	// The file holds the same records, and IDs are not reused.
+	again := openRoster(t, path)
+	if got, want := again.Query(), r.Query(); !reflect.DeepEqual(got, want) {
+		t.Errorf("reopened roster:\n%+v\nwant:\n%+v", got, want)
+	}
+	if rec, _ := again.Get(3); rec.Student.Age != 99 {
+		t.Errorf("reopened Age = %d, want 99", rec.Student.Age)
+	}
+	if id, _ := again.Create(Record{Teacher: &Teacher{Human: human("Fay", 50, "Xi'an")}}); id != 6 {
+		t.Errorf("next ID after reopening = %d, want 6", id)
+	}
+}
+
+func TestRosterQuery(t *testing.T) {
+	r := openRoster(t, filepath.Join(t.TempDir(), "roster.jsonl"))
+	fillRoster(t, r)
+	tests := []struct {
+		filters []Filter
+		want    string
+	}{
+		{nil, "Ann Bob Cai Dan Eve"},
+		{[]Filter{AgeBetween(18, 20)}, "Cai Dan Eve"},
+		{[]Filter{AgeBetween(20, 20)}, "Dan"},
+		{[]Filter{InClass("One")}, "Cai Eve"},
+		{[]Filter{Teaches("CS")}, "Ann"},
+		{[]Filter{InCity("Beijing")}, "Ann Cai Dan"},
+		{[]Filter{InCity("Beijing"), InClass("One")}, "Cai"},
+		{[]Filter{InCity("Shanghai"), Teaches("CS")}, ""},
+	}
+	for i, tt := range tests {
+		if got := names(r.Query(tt.filters...)); got != tt.want {
+			t.Errorf("query %d = %q, want %q", i, got, tt.want)
+		}
+	}
+}
+
+func TestRosterCSV(t *testing.T) {
+	dir := t.TempDir()
+	r := openRoster(t, filepath.Join(dir, "a.jsonl"))
+	fillRoster(t, r)
+	// Commas, quotes and line breaks survive the round trip.
+	rec, _ := r.Get(1)
+	rec.Teacher.Contact.Address = "1 \"Main\" St,\nBeijing"
+	r.Update(rec)
+
+	var csv strings.Builder
+	if err := r.ExportCSV(&csv); err != nil {
+		t.Fatal(err)
+	}
+	other := openRoster(t, filepath.Join(dir, "b.jsonl"))
+	if n, err := other.ImportCSV(strings.NewReader(csv.String())); err != nil || n != 5 {
+		t.Fatalf("ImportCSV = %d, %v; want 5, nil", n, err)
+	}
+	if got, want := other.Query(), r.Query(); !reflect.DeepEqual(got, want) {
+		t.Errorf("imported:\n%+v\nwant:\n%+v", got, want)
+	}
+
+	// Importing again gives the rows new IDs, since theirs are taken.
+	if n, _ := other.ImportCSV(strings.NewReader(csv.String())); n != 5 {
+		t.Fatalf("second ImportCSV added %d, want 5", n)
+	}
+	if recs := other.Query(); len(recs) != 10 || recs[9].ID != 10 {
+		t.Errorf("after second import: %d records, last ID %d; want 10, 10", len(recs), recs[9].ID)
+	}
+
+	// One bad row and nothing is imported.
+	bad := csv.String() + "7,janitor,Gus,male,60,Beijing,,,\n"
+	if _, err := other.ImportCSV(strings.NewReader(bad)); err == nil {
+		t.Error("ImportCSV with an unknown role succeeded")
+	}
+	if _, err := other.ImportCSV(strings.NewReader("id,name\n1,Ann\n")); err == nil {
+		t.Error("ImportCSV with missing columns succeeded")
+	}
+	if len(other.Query()) != 10 {
+		t.Errorf("failed imports changed the roster")
+	}
+}
+
+// failSaves makes the next saves of r fail: a directory now stands where
+// the roster file goes, so the rename cannot replace it.
+func failSaves(t *testing.T, r *Roster) {
+	t.Helper()
+	os.Remove(r.path)
+	if err := os.MkdirAll(filepath.Join(r.path, "blocker"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRosterFailedSave(t *testing.T) {
+	dir := t.TempDir()
+	r := openRoster(t, filepath.Join(dir, "roster.jsonl"))
+	fillRoster(t, r)
+	failSaves(t, r)
+
+	rec := Record{Student: &Student{Human: human("Gus", 7, "Beijing")}}
+	if _, err := r.Create(rec); err == nil {
+		t.Fatal("Create succeeded with the roster file blocked")
+	}
+	csv := "id,role,name,gender,age,address,tel,subject,class\n,student,Hal,male,8,,,,\n"
+	if _, err := r.ImportCSV(strings.NewReader(csv)); err == nil {
+		t.Fatal("ImportCSV succeeded with the roster file blocked")
+	}
+	if err := r.Delete(1); err == nil {
+		t.Fatal("Delete succeeded with the roster file blocked")
+	}
+	if names(r.Query()) != "Ann Bob Cai Dan Eve" {
+		t.Errorf("failed changes left %q", names(r.Query()))
+	}
+	// No temporary file is left behind.
+	if tmps, _ := filepath.Glob(filepath.Join(dir, "roster.jsonl.tmp*")); len(tmps) != 0 {
+		t.Errorf("temporary files left: %v", tmps)
+	}
+
+	// The failed Create and ImportCSV gave back the IDs they took.
+	os.RemoveAll(r.path)
+	if id, err := r.Create(rec); err != nil || id != 6 {
+		t.Errorf("Create after failures = %d, %v; want 6, nil", id, err)
+	}
+}
@@ -2,16 +2,21 @@ package main
 
 import (
 	"fmt"
+	"os"
 )
 
 // A struct is a collection of fields.
 
+// A field can be followed by a tag, a string that reflection can read.
+// Here the tags hold the rules checked by Validate.
+
 type Human struct {
-	Name    string
-	Gender  string
-	Age     int
+	Name    string `validate:"required"`
+	Gender  string `validate:"oneof=male female"`
+	Age     int    `validate:"min=0,max=150"`
 	Contact struct {
-		Address, Tel string
+		Address string
+		Tel     string `validate:"phone"`
 	}
 }
 
@@ -35,6 +40,16 @@ type SubType struct {
 }
 
 func main() {
+	// go run $(ls *.go | grep -v _test.go) contacts import|export ...
+	// manages the roster from vCards.
+	if len(os.Args) > 1 && os.Args[1] == "contacts" {
+		if err := contactsCommand(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Go has pointers. A pointer holds the memory address of a value.
 	// The type *T is a pointer to a T value. Its zero value is nil.
 	i := 42
@@ -81,4 +96,61 @@ func main() {
 	// permits us instead to write just p.X, without the explicit dereference.
 	pa := &a
 	fmt.Println(pa.Name) // name-sub
+
+	rosterMain(t, s)
+	// 2 {{Daniel male 19 {Beijing 19911223344}} One}
+	// 1 Tom
+	// 1
+	// id,role,name,gender,age,address,tel,subject,class
+	// 2,student,Daniel,male,19,Beijing,19911223344,,One
+
+	validateMain(t)
+	// <nil>
+	// Teacher.Human.Gender: unknown breaks rule "oneof=male female"
+	// Teacher.Human.Age: -1 breaks rule "min=0"
+	// Teacher.Human.Contact.Tel: call me breaks rule "phone"
+	// Teacher.Human.Gender
+
+	prettyMain(t)
+	// main.Teacher
+	//   Human main.Human (embedded)
+	//     Name string = "Tom"
+	//     Gender string = "male"
+	//     Age int = 30
+	//     Contact struct{...}
+	//       Address string = "Beijing"
+	//       Tel string = "18833445566"
+	//   Subject string = "CS"
+	// main.Teacher
+	//   Human main.Human (embedded) {...}
+	//   Subject string = "CS"
+	// &main.node{name: "a", next: &main.node{name: "b", next: <cycle>}}
+
+	diffMain(t)
+	// Human.Age: 30 → 31
+	// Human.Contact.Tel: "18833445566" → "19911223344"
+	// true
+	// ["scores"][1]: added 80
+	// ["rank"]: added [1]
+	// [
+	//   {
+	//     "path": "[\"scores\"][1]",
+	//     "kind": "added",
+	//     "new": 80
+	//   },
+	//   {
+	//     "path": "[\"rank\"]",
+	//     "kind": "added",
+	//     "new": [
+	//       1
+	//     ]
+	//   }
+	// ]
+
+	contactsMain()
+	// Li Lei [{18833445566 [cell voice]} {010-12345678 [work]}]
+	// No. 1, Zhongguancun Street
+	// {Li Lei male 0 {Beijing 18833445566}}
+	// 3.0 true
+	// 4.0 true
 }
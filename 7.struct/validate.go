@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Struct tags attach metadata to fields, which can be read through reflection:
+//
+//	Age int `validate:"min=0,max=150"`
+//
+// Validate walks a struct, including embedded and nested structs, and checks
+// each field against the rules in its validate tag:
+//
+//	required   the field is not its zero value
+//	min=N      a number is at least N, a string or slice has at least N elements
+//	max=N      a number is at most N, a string or slice has at most N elements
+//	oneof=a b  the value, formatted with %v, is one of the listed words
+//	phone      a phone number: digits, spaces or dashes, optionally after a +
+//
+// Reflection can read unexported fields, so they are checked like the others,
+// whether they are tagged themselves or sit inside an unexported struct.
+
+// FieldError is one rule broken by one field.
+type FieldError struct {
+	Path  string // e.g. Teacher.Human.Contact.Tel
+	Rule  string // e.g. max=150
+	Value interface{}
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %v breaks rule %q", e.Path, e.Value, e.Rule)
+}
+
+// ValidationErrors lists every broken rule, in field order.
+type ValidationErrors []*FieldError
+
+func (es ValidationErrors) Error() string {
+	msgs := make([]string, len(es))
+	for i, e := range es {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap lets errors.As find the individual FieldErrors.
+func (es ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(es))
+	for i, e := range es {
+		errs[i] = e
+	}
+	return errs
+}
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9][0-9 -]{4,18}[0-9]$`)
+
+// Validate checks v, a struct or a pointer to one, and returns
+// ValidationErrors listing every violation, or nil.
+func Validate(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	seen := make(map[uintptr]bool)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return errors.New("validate: nil pointer")
+		}
+		seen[rv.Pointer()] = true
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("validate: %s is not a struct", rv.Type())
+	}
+	var errs ValidationErrors
+	if err := validateStruct(rv, rv.Type().Name(), seen, &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// seen holds the pointers followed on the path from the root, so a struct
+// that points back to itself is checked once rather than forever.
+func validateStruct(rv reflect.Value, path string, seen map[uintptr]bool, errs *ValidationErrors) error {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fv := rv.Field(i)
+		fpath := f.Name
+		if path != "" {
+			fpath = path + "." + f.Name
+		}
+
+		if tag, ok := f.Tag.Lookup("validate"); ok && tag != "" {
+			for _, rule := range strings.Split(tag, ",") {
+				ok, err := checkRule(fv, rule)
+				if err != nil {
+					return fmt.Errorf("validate: %s: %v", fpath, err)
+				}
+				if !ok {
+					*errs = append(*errs, &FieldError{Path: fpath, Rule: rule, Value: interfaceOf(fv)})
+				}
+			}
+		}
+
+		// Descend into embedded structs such as Teacher.Human and anonymous
+		// ones such as Human.Contact, following non-nil pointers.
+		var followed []uintptr
+		for fv.Kind() == reflect.Pointer && !fv.IsNil() && !seen[fv.Pointer()] {
+			followed = append(followed, fv.Pointer())
+			seen[fv.Pointer()] = true
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct {
+			if err := validateStruct(fv, fpath, seen, errs); err != nil {
+				return err
+			}
+		}
+		for _, ptr := range followed {
+			delete(seen, ptr)
+		}
+	}
+	return nil
+}
+
+// checkRule reports whether v satisfies one rule. An error means the rule
+// itself is malformed or does not apply to the field's type.
+func checkRule(v reflect.Value, rule string) (bool, error) {
+	name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+	switch name {
+	case "required":
+		return !v.IsZero(), nil
+	case "min", "max":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return false, fmt.Errorf("bad %s limit %q", name, arg)
+		}
+		var n float64
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = float64(v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			n = float64(v.Uint())
+		case reflect.Float32, reflect.Float64:
+			n = v.Float()
+		case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+			n = float64(v.Len())
+		default:
+			return false, fmt.Errorf("%s does not apply to %s", name, v.Type())
+		}
+		if name == "min" {
+			return n >= limit, nil
+		}
+		return n <= limit, nil
+	case "oneof":
+		s := fmt.Sprint(interfaceOf(v))
+		for _, w := range strings.Fields(arg) {
+			if s == w {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "phone":
+		if v.Kind() != reflect.String {
+			return false, fmt.Errorf("phone does not apply to %s", v.Type())
+		}
+		// An empty number is allowed; combine with required to forbid it.
+		return v.Len() == 0 || phonePattern.MatchString(v.String()), nil
+	}
+	return false, fmt.Errorf("unknown rule %q", rule)
+}
+
+// interfaceOf returns v's value. A field reached through an unexported
+// struct field cannot be turned back into an interface{}, so its value is
+// formatted instead; fmt can still read it.
+func interfaceOf(v reflect.Value) interface{} {
+	if v.CanInterface() {
+		return v.Interface()
+	}
+	return fmt.Sprint(v)
+}
+
+func validateMain(t Teacher) {
+	fmt.Println(Validate(t)) // <nil>
+
+	t.Gender = "unknown"
+	t.Age = -1
+	t.Contact.Tel = "call me"
+	err := Validate(&t)
+	fmt.Println(err)
+	// Teacher.Human.Gender: unknown breaks rule "oneof=male female"
+	// Teacher.Human.Age: -1 breaks rule "min=0"
+	// Teacher.Human.Contact.Tel: call me breaks rule "phone"
+
+	var fe *FieldError
+	if errors.As(err, &fe) {
+		fmt.Println(fe.Path) // Teacher.Human.Gender
+	}
+}
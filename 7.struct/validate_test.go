@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type account struct {
+	User  string   `validate:"required,min=3,max=8"`
+	Score float64  `validate:"min=0,max=1"`
+	Count uint     `validate:"max=10"`
+	Tags  []string `validate:"max=2"`
+	Plan  string   `validate:"oneof=free pro"`
+	Level int      `validate:"oneof=1 2 3"`
+	Tel   string   `validate:"phone"`
+	Next  *account
+	inner struct {
+		code string `validate:"required"`
+	}
+}
+
+func goodAccount() account {
+	a := account{User: "ann", Score: 0.5, Plan: "free", Level: 1, Tel: "+86 138-0000-0000"}
+	a.inner.code = "x"
+	return a
+}
+
+func TestValidateRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(a *account)
+		want   string // the broken rules, "" for none
+	}{
+		{"valid", func(a *account) {}, ""},
+		{"required", func(a *account) { a.User = "" }, "account.User required, account.User min=3"},
+		{"min string", func(a *account) { a.User = "al" }, "account.User min=3"},
+		{"max string", func(a *account) { a.User = "alexander" }, "account.User max=8"},
+		{"min float", func(a *account) { a.Score = -0.1 }, "account.Score min=0"},
+		{"max float", func(a *account) { a.Score = 1.5 }, "account.Score max=1"},
+		{"max uint", func(a *account) { a.Count = 11 }, "account.Count max=10"},
+		{"max slice", func(a *account) { a.Tags = []string{"a", "b", "c"} }, "account.Tags max=2"},
+		{"oneof", func(a *account) { a.Plan = "gold" }, "account.Plan oneof=free pro"},
+		{"oneof int", func(a *account) { a.Level = 4 }, "account.Level oneof=1 2 3"},
+		{"phone", func(a *account) { a.Tel = "call me" }, "account.Tel phone"},
+		{"short phone", func(a *account) { a.Tel = "123" }, "account.Tel phone"},
+		{"empty phone", func(a *account) { a.Tel = "" }, ""},
+		{"nested pointer", func(a *account) { a.Next = &account{User: "bo", Plan: "pro", Level: 2} },
+			"account.Next.User min=3, account.Next.inner.code required"},
+		{"unexported", func(a *account) { a.inner.code = "" }, "account.inner.code required"},
+		{"cycle", func(a *account) { a.Next = a }, ""},
+	}
+	for _, tt := range tests {
+		a := goodAccount()
+		tt.change(&a)
+		err := Validate(&a)
+		var got []string
+		var verrs ValidationErrors
+		if errors.As(err, &verrs) {
+			for _, fe := range verrs {
+				got = append(got, fe.Path+" "+fe.Rule)
+			}
+		} else if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if strings.Join(got, ", ") != tt.want {
+			t.Errorf("%s: broken rules %q, want %q", tt.name, strings.Join(got, ", "), tt.want)
+		}
+	}
+}
+
+func TestValidateTeacher(t *testing.T) {
+	var teacher Teacher
+	teacher.Name, teacher.Gender, teacher.Age = "Tom", "male", 200
+	teacher.Contact.Tel = "12"
+	err := Validate(teacher)
+	want := `Teacher.Human.Age: 200 breaks rule "max=150"
+Teacher.Human.Contact.Tel: 12 breaks rule "phone"`
+	if err == nil || err.Error() != want {
+		t.Errorf("Validate = %v, want:\n%s", err, want)
+	}
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Value != 200 {
+		t.Errorf("errors.As found %+v, want the Age error", fe)
+	}
+}
+
+func TestValidateBadInput(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+	}{
+		{"nil pointer", (*account)(nil)},
+		{"not a struct", 3},
+		{"unknown rule", struct {
+			X int `validate:"even"`
+		}{}},
+		{"bad limit", struct {
+			X int `validate:"min=a"`
+		}{}},
+		{"min on bool", struct {
+			X bool `validate:"min=1"`
+		}{}},
+		{"phone on int", struct {
+			X int `validate:"phone"`
+		}{}},
+	}
+	for _, tt := range tests {
+		err := Validate(tt.v)
+		var verrs ValidationErrors
+		if err == nil || errors.As(err, &verrs) {
+			t.Errorf("%s: Validate = %v, want a usage error", tt.name, err)
+		}
+	}
+}
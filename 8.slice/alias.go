@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unsafe"
+)
+
+// printSlice shows len and cap, but not which slices look at the same array.
+// unsafe.SliceData returns a pointer to a slice's first element, so two slices
+// share a backing array when their element ranges, extended to capacity,
+// overlap in memory. A full slice expression such as b[0:3:5] ends its
+// capacity before the end of the array, so slices of one array need not end
+// at the same address; and two slices that do not overlap may still be joined
+// by a third one that overlaps both. Each array drawn spans the elements seen.
+//
+// Addresses are only compared while the arrays are alive: once an array is
+// garbage, the allocator may hand its memory to a new one. So a view keeps a
+// pointer to its slice's first element, which keeps the whole array alive for
+// as long as the AliasMap is.
+
+// A sliceView is one named slice as seen by the visualiser.
+type sliceView struct {
+	name     string
+	data     unsafe.Pointer // first element, nil when cap is 0
+	size     uintptr        // element size
+	len, cap int
+}
+
+// start and end are the addresses of the first element and of the end of
+// the capacity.
+func (v sliceView) start() uintptr { return uintptr(v.data) }
+func (v sliceView) end() uintptr   { return uintptr(v.data) + uintptr(v.cap)*v.size }
+
+func (v sliceView) overlaps(w sliceView) bool {
+	return v.start() < w.end() && w.start() < v.end()
+}
+
+// An arrayGroup is a set of slices known to share one backing array.
+type arrayGroup struct {
+	start, end uintptr
+	views      []sliceView
+}
+
+// AliasMap records named slices of one element type and draws which ones
+// share a backing array. Tracking a name again after an append shows whether
+// append reused the array or moved the slice to a new one.
+type AliasMap[T any] struct {
+	views []sliceView
+	prev  map[string]sliceView
+	notes []string
+}
+
+// Track adds or updates the slice called name.
+func (m *AliasMap[T]) Track(name string, s []T) {
+	var zero T
+	v := sliceView{name: name, size: unsafe.Sizeof(zero), len: len(s), cap: cap(s)}
+	if cap(s) > 0 {
+		v.data = unsafe.Pointer(unsafe.SliceData(s[:1]))
+	}
+
+	if m.prev == nil {
+		m.prev = make(map[string]sliceView)
+	}
+	if old, ok := m.prev[name]; ok && old.cap > 0 && v.cap > 0 {
+		if !old.overlaps(v) {
+			m.notes = append(m.notes, fmt.Sprintf(
+				"%s: reallocated (cap %d -> %d), no longer shares the old array", name, old.cap, v.cap))
+		} else if v.len > old.len {
+			m.notes = append(m.notes, fmt.Sprintf(
+				"%s: grew in place (len %d -> %d), same backing array", name, old.len, v.len))
+		}
+	}
+	m.prev[name] = v
+
+	for i := range m.views {
+		if m.views[i].name == name {
+			m.views[i] = v
+			return
+		}
+	}
+	m.views = append(m.views, v)
+}
+
+// groups returns the arrays, each with the slices that point into it.
+// Slices with no capacity have no array and are left out.
+func (m *AliasMap[T]) groups() []arrayGroup {
+	var gs []arrayGroup
+	for _, v := range m.views {
+		if v.cap == 0 {
+			continue
+		}
+		// v joins every group whose range it overlaps, which merges them
+		// into one.
+		g := arrayGroup{start: v.start(), end: v.end()}
+		rest := gs[:0]
+		for _, other := range gs {
+			if other.start < g.end && g.start < other.end {
+				g.start, g.end = min(g.start, other.start), max(g.end, other.end)
+				g.views = append(g.views, other.views...)
+			} else {
+				rest = append(rest, other)
+			}
+		}
+		g.views = append(g.views, v)
+		gs = append(rest, g)
+	}
+	for _, g := range gs {
+		sort.SliceStable(g.views, func(i, j int) bool { return m.order(g.views[i]) < m.order(g.views[j]) })
+	}
+	sort.Slice(gs, func(i, j int) bool { return gs[i].start < gs[j].start })
+	return gs
+}
+
+// order returns the position of v's name in the order of first tracking.
+func (m *AliasMap[T]) order(v sliceView) int {
+	for i := range m.views {
+		if m.views[i].name == v.name {
+			return i
+		}
+	}
+	return len(m.views)
+}
+
+// Shared reports whether the slices named a and b share a backing array.
+func (m *AliasMap[T]) Shared(a, b string) bool {
+	for _, g := range m.groups() {
+		var hasA, hasB bool
+		for _, v := range g.views {
+			hasA = hasA || v.name == a
+			hasB = hasB || v.name == b
+		}
+		if hasA && hasB {
+			return true
+		}
+	}
+	return false
+}
+
+// String draws each backing array as a row of cells, one per element seen,
+// with one line per slice: '=' for elements within its length, '-' for the
+// spare capacity after them.
+func (m *AliasMap[T]) String() string {
+	var zero T
+	size := unsafe.Sizeof(zero)
+	if size == 0 {
+		size = 1
+	}
+	var b strings.Builder
+	width := 0
+	for _, v := range m.views {
+		width = max(width, len(v.name))
+	}
+	for i, g := range m.groups() {
+		n := int((g.end - g.start) / size)
+		fmt.Fprintf(&b, "array %d: %d elements seen\n", i+1, n)
+		fmt.Fprintf(&b, "  %-*s  |%s|\n", width, "", strings.Repeat("___", n))
+		for _, v := range g.views {
+			first := int((v.start() - g.start) / size)
+			row := strings.Repeat("   ", first) +
+				strings.Repeat("===", v.len) + strings.Repeat("---", v.cap-v.len)
+			fmt.Fprintf(&b, "  %-*s  |%-*s| [%d:%d] len=%d cap=%d\n",
+				width, v.name, 3*n, row, first, first+v.len, v.len, v.cap)
+		}
+	}
+	for _, v := range m.views {
+		if v.cap == 0 {
+			fmt.Fprintf(&b, "%s: no backing array\n", v.name)
+		}
+	}
+	for _, note := range m.notes {
+		b.WriteString(note + "\n")
+	}
+	return b.String()
+}
+
+func aliasMain() {
+	b := [10]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	var m AliasMap[int]
+	m.Track("s1", b[5:8])
+	m.Track("s2", b[:3])
+	s3 := make([]int, 3, 6)
+	m.Track("s3", s3)
+
+	s3 = append(s3, 1, 2, 3)
+	m.Track("s3", s3)
+	s3 = append(s3, 1, 2, 3)
+	m.Track("s3", s3)
+	fmt.Print(m.String())
+	// array 1: 10 elements seen
+	//       |______________________________|
+	//   s1  |               =========------| [5:8] len=3 cap=5
+	//   s2  |=========---------------------| [0:3] len=3 cap=10
+	// array 2: 12 elements seen
+	//       |____________________________________|
+	//   s3  |===========================---------| [0:9] len=9 cap=12
+	// s3: grew in place (len 3 -> 6), same backing array
+	// s3: reallocated (cap 6 -> 12), no longer shares the old array
+	fmt.Println(m.Shared("s1", "s2"), m.Shared("s1", "s3")) // true false
+
+	// x's capacity stops at b[5] and y starts at b[6]: nothing connects
+	// them until z overlaps both.
+	var full AliasMap[int]
+	full.Track("x", b[0:3:5])
+	full.Track("y", b[6:8])
+	fmt.Println(full.Shared("x", "y")) // false
+	full.Track("z", b[2:8])
+	fmt.Print(full.String())
+	// array 1: 10 elements seen
+	//      |______________________________|
+	//   x  |=========------               | [0:3] len=3 cap=5
+	//   y  |                  ======------| [6:8] len=2 cap=4
+	//   z  |      ==================------| [2:8] len=6 cap=8
+	fmt.Println(full.Shared("x", "y")) // true
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestAliasShared(t *testing.T) {
+	a := make([]int, 4, 8)
+	var m AliasMap[int]
+	m.Track("a", a)
+	m.Track("head", a[:2])
+	m.Track("tail", a[6:8]) // within a's capacity, past its length
+	m.Track("other", make([]int, 4))
+	m.Track("empty", a[:0:0])
+
+	tests := []struct {
+		x, y string
+		want bool
+	}{
+		{"a", "head", true},
+		{"a", "tail", true},
+		{"head", "tail", true}, // joined through a
+		{"a", "other", false},
+		{"a", "empty", false}, // no capacity, no array
+		{"a", "missing", false},
+	}
+	for _, tt := range tests {
+		if got := m.Shared(tt.x, tt.y); got != tt.want {
+			t.Errorf("Shared(%s, %s) = %v, want %v", tt.x, tt.y, got, tt.want)
+		}
+	}
+	if !strings.Contains(m.String(), "empty: no backing array\n") {
+		t.Errorf("String does not report the empty slice:\n%s", m.String())
+	}
+}
+
+func TestAliasSubslices(t *testing.T) {
+	b := make([]byte, 8)
+	var m AliasMap[byte]
+	m.Track("x", b[0:2:3])
+	m.Track("y", b[4:6])
+	if m.Shared("x", "y") {
+		t.Error("x and y share an array before anything joins them")
+	}
+	m.Track("z", b[1:5])
+	if !m.Shared("x", "y") {
+		t.Error("z overlaps x and y, but they are not joined")
+	}
+	want := `array 1: 8 elements seen
+     |________________________|
+  x  |======---               | [0:2] len=2 cap=3
+  y  |            ======------| [4:6] len=2 cap=4
+  z  |   ============---------| [1:5] len=4 cap=7
+`
+	if got := m.String(); got != want {
+		t.Errorf("String:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestAliasRegrown(t *testing.T) {
+	var m AliasMap[int]
+	s := make([]int, 1, 2)
+	m.Track("s", s)
+	s = append(s, 1)
+	m.Track("s", s)
+	old := s
+	m.Track("old", old)
+	s = append(s, 2) // over capacity: a new array
+	m.Track("s", s)
+	want := `s: grew in place (len 1 -> 2), same backing array
+s: reallocated (cap 2 -> 4), no longer shares the old array
+`
+	if got := m.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("String:\n%s\nwant it to end with:\n%s", got, want)
+	}
+	if m.Shared("s", "old") {
+		t.Error("s still shares an array with old after reallocating")
+	}
+}
+
+// TestAliasKeepsArraysAlive drops every other reference to a tracked array
+// and allocates many arrays of the same size. Were the array freed, one of
+// them could take its address and look shared with it.
+func TestAliasKeepsArraysAlive(t *testing.T) {
+	var m AliasMap[int64]
+	m.Track("gone", heapArray())
+	for i := range 1000 {
+		if i%100 == 0 {
+			runtime.GC()
+		}
+		m.Track("new", heapArray())
+		if m.Shared("gone", "new") {
+			t.Fatalf("a new array shares the tracked one's memory after %d allocations", i)
+		}
+	}
+}
+
+// heapArray returns a new array on the heap: storing it in escape, even
+// briefly, stops the compiler from putting it on the stack.
+func heapArray() []int64 {
+	s := make([]int64, 16)
+	escape = s
+	escape = nil
+	return s
+}
+
+var escape []int64
@@ -0,0 +1,86 @@
+package main
+
+import "testing"
+
+// Run with: cd 8.slice && go test -bench . -benchmem *.go
+
+var benchData = func() []int {
+	data := make([]int, 100000)
+	for i := range data {
+		data[i] = i
+	}
+	return data
+}()
+
+// Sum of the squares of the even numbers, built eagerly with a slice per
+// step and lazily as one pipeline.
+
+func BenchmarkEagerSlices(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var evens []int
+		for _, v := range benchData {
+			if v%2 == 0 {
+				evens = append(evens, v)
+			}
+		}
+		squares := make([]int, 0, len(evens))
+		for _, v := range evens {
+			squares = append(squares, v*v)
+		}
+		sum := 0
+		for _, v := range squares {
+			sum += v
+		}
+		benchSink = sum
+	}
+}
+
+func sumEvenSquares(data []int) int {
+	evens := Filter(FromSlice(data), func(v int) bool { return v%2 == 0 })
+	sum := 0
+	for v := range Map(evens, func(v int) int { return v * v }) {
+		sum += v
+	}
+	return sum
+}
+
+func sumWindows(data []int) int {
+	sum := 0
+	for w := range Window(Dedup(FromSlice(data)), 8) {
+		sum += w[0]
+	}
+	return sum
+}
+
+func BenchmarkPipeline(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchSink = sumEvenSquares(benchData)
+	}
+}
+
+func BenchmarkPipelineWindows(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchSink = sumWindows(benchData)
+	}
+}
+
+// TestPipelineAllocs checks that a pipeline builds no intermediate slices:
+// Filter and Map allocate nothing, and Window only its one reused buffer,
+// however long the input.
+func TestPipelineAllocs(t *testing.T) {
+	for _, n := range []int{10, 100000} {
+		data := benchData[:n]
+		if got := testing.AllocsPerRun(10, func() { benchSink = sumEvenSquares(data) }); got != 0 {
+			t.Errorf("Filter and Map over %d values: %v allocations, want 0", n, got)
+		}
+		if got := testing.AllocsPerRun(10, func() { benchSink = sumWindows(data) }); got != 1 {
+			t.Errorf("Window over %d values: %v allocations, want 1", n, got)
+		}
+	}
+}
+
+// The sink keeps the compiler from optimising the benchmarked work away.
+var benchSink int
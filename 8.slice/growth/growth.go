@@ -0,0 +1,144 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"text/tabwriter"
+	"unsafe"
+)
+
+// When append runs out of capacity it allocates a bigger array and copies the
+// elements over. The new capacity roughly doubles for small slices and grows
+// by about 1.25x for large ones, and is then rounded up to an allocator size
+// class, so the exact numbers depend on the element size.
+
+// growth appends elements one at a time for several element sizes and
+// records every reallocation, the growth factor, and the bytes allocated
+// along the way, then compares that with a slice made with enough capacity.
+//
+// Usage:
+//
+//	go run 8.slice/growth/growth.go [-n 10000] [-curve]
+func main() {
+	n := flag.Int("n", 10000, "number of elements to append")
+	curve := flag.Bool("curve", false, "print every reallocation, not only the summary")
+	flag.Parse()
+	if *n <= 0 {
+		fmt.Fprintln(os.Stderr, "growth: -n must be positive")
+		os.Exit(2)
+	}
+
+	reports := []report{
+		measure[[1]byte](*n),
+		measure[int64](*n),
+		measure[[24]byte](*n),
+		measure[[64]byte](*n),
+	}
+
+	if *curve {
+		for _, r := range reports {
+			r.printCurve(os.Stdout)
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "elem size\treallocs\tfinal cap\tbytes allocated\tallocs/run\tpre-sized bytes\tpre-sized allocs/run\t\n")
+	for _, r := range reports {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%.0f\t%d\t%.0f\t\n", r.size, len(r.steps), r.finalCap,
+			r.bytes, r.allocs, r.presizedBytes, r.presizedAllocs)
+	}
+	w.Flush()
+
+	fmt.Println()
+	for _, r := range reports {
+		fmt.Printf("%dB elements: make([]T, 0, %d) saves %d reallocations and %s of copying garbage\n",
+			r.size, *n, len(r.steps)-1, bytes(r.bytes-r.presizedBytes))
+	}
+}
+
+// A step is one reallocation by append.
+type step struct {
+	len            int // length of the slice when append reallocated
+	oldCap, newCap int
+}
+
+type report struct {
+	size           uintptr
+	steps          []step
+	finalCap       int
+	bytes          int64 // sum of the sizes of every array append allocated
+	allocs         float64
+	presizedBytes  int64
+	presizedAllocs float64
+}
+
+// measure appends n zero values of T to a nil slice.
+func measure[T any](n int) report {
+	var zero T
+	r := report{size: unsafe.Sizeof(zero)}
+
+	var s []T
+	var data *T
+	for i := 0; i < n; i++ {
+		oldCap := cap(s)
+		s = append(s, zero)
+		// A new data pointer means a new array: the old one is garbage now.
+		if d := unsafe.SliceData(s); d != data {
+			data = d
+			r.steps = append(r.steps, step{len: i, oldCap: oldCap, newCap: cap(s)})
+			r.bytes += int64(cap(s)) * int64(r.size)
+		}
+	}
+	r.finalCap = cap(s)
+
+	r.allocs = testing.AllocsPerRun(20, func() {
+		var s []T
+		for i := 0; i < n; i++ {
+			s = append(s, zero)
+		}
+		sink = len(s)
+	})
+	r.presizedBytes = int64(n) * int64(r.size)
+	r.presizedAllocs = testing.AllocsPerRun(20, func() {
+		s := make([]T, 0, n)
+		for i := 0; i < n; i++ {
+			s = append(s, zero)
+		}
+		sink = len(s)
+	})
+	return r
+}
+
+// sink keeps the compiler from optimising the measured appends away.
+var sink int
+
+func (r report) printCurve(out io.Writer) {
+	fmt.Fprintf(out, "%d-byte elements\n", r.size)
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "at len\told cap\tnew cap\tfactor\t\n")
+	for _, s := range r.steps {
+		factor, bar := "-", ""
+		if s.oldCap > 0 {
+			f := float64(s.newCap) / float64(s.oldCap)
+			factor = fmt.Sprintf("%.2f", f)
+			bar = strings.Repeat("#", int(f*10))
+		}
+		fmt.Fprintf(w, "%d\t%d\t%d\t%s\t %s\n", s.len, s.oldCap, s.newCap, factor, bar)
+	}
+	w.Flush()
+	fmt.Fprintln(out)
+}
+
+func bytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMeasure(t *testing.T) {
+	const n = 5000
+	check := func(r report) {
+		t.Helper()
+		if len(r.steps) == 0 || r.steps[0] != (step{len: 0, oldCap: 0, newCap: r.steps[0].newCap}) {
+			t.Fatalf("%dB: first step %+v, want one at len 0 from cap 0", r.size, r.steps)
+		}
+		var bytes int64
+		for i, s := range r.steps {
+			// append reallocates exactly when the slice is full.
+			if s.len != s.oldCap || s.newCap <= s.oldCap {
+				t.Errorf("%dB: step %d = %+v", r.size, i, s)
+			}
+			if i > 0 && s.oldCap != r.steps[i-1].newCap {
+				t.Errorf("%dB: step %d starts at cap %d, the last one ended at %d", r.size, i, s.oldCap, r.steps[i-1].newCap)
+			}
+			// Between 1.25x and a little over 2x, after size class rounding.
+			if f := float64(s.newCap) / float64(s.oldCap); i > 0 && (f < 1.2 || f > 2.5) {
+				t.Errorf("%dB: step %d grows by %.2f", r.size, i, f)
+			}
+			bytes += int64(s.newCap) * int64(r.size)
+		}
+		last := r.steps[len(r.steps)-1]
+		if r.finalCap != last.newCap || r.finalCap < n {
+			t.Errorf("%dB: final cap %d, last step %+v, n %d", r.size, r.finalCap, last, n)
+		}
+		if r.bytes != bytes || r.presizedBytes != n*int64(r.size) {
+			t.Errorf("%dB: bytes %d and %d, want %d and %d", r.size, r.bytes, r.presizedBytes, bytes, n*int64(r.size))
+		}
+		// Every reallocation is one allocation, except that the compiler
+		// may put the first, small array on the stack. A pre-sized slice
+		// needs one.
+		if steps := float64(len(r.steps)); r.allocs > steps || r.allocs < steps-1 || r.presizedAllocs != 1 {
+			t.Errorf("%dB: %v allocs for %d steps, %v pre-sized", r.size, r.allocs, len(r.steps), r.presizedAllocs)
+		}
+	}
+	check(measure[[1]byte](n))
+	check(measure[int64](n))
+	check(measure[[24]byte](n))
+}
+
+func TestPrintCurve(t *testing.T) {
+	r := report{size: 8, steps: []step{{0, 0, 1}, {1, 1, 2}, {2, 2, 4}, {4, 4, 8}, {8, 8, 10}}}
+	var b strings.Builder
+	r.printCurve(&b)
+	want := `8-byte elements
+  at len  old cap  new cap  factor
+       0        0        1       - 
+       1        1        2    2.00 ####################
+       2        2        4    2.00 ####################
+       4        4        8    2.00 ####################
+       8        8       10    1.25 ############
+
+`
+	if got := b.String(); got != want {
+		t.Errorf("printCurve:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{3 << 20, "3.0 MiB"},
+	}
+	for _, tt := range tests {
+		if got := bytes(tt.n); got != tt.want {
+			t.Errorf("bytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Slices can contain other slices, so [][]float64 is the obvious matrix, but
+// every row is then a separate array somewhere on the heap. Matrix keeps all
+// its elements in one slice, row after row. Like s1 := b[5:8], a row, column
+// or block of a Matrix is a view that shares the same backing array, so
+// writing through a view changes the original.
+
+// Number is the set of element types a Matrix can hold.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// Matrix is a dense rows×cols matrix stored in row-major order.
+// Element (i, j) is data[i*stride+j]; stride is larger than cols in a view
+// that covers only some of the columns of its parent.
+type Matrix[T Number] struct {
+	rows, cols, stride int
+	data               []T
+}
+
+// NewMatrix returns a zero rows×cols matrix.
+func NewMatrix[T Number](rows, cols int) Matrix[T] {
+	if rows < 0 || cols < 0 {
+		panic("NewMatrix: negative dimension")
+	}
+	return Matrix[T]{rows, cols, cols, make([]T, rows*cols)}
+}
+
+// MatrixOf returns a matrix with the given rows, which must have equal lengths.
+func MatrixOf[T Number](rows ...[]T) Matrix[T] {
+	if len(rows) == 0 {
+		return Matrix[T]{}
+	}
+	m := NewMatrix[T](len(rows), len(rows[0]))
+	for i, r := range rows {
+		if len(r) != m.cols {
+			panic(fmt.Sprintf("MatrixOf: row %d has %d elements, want %d", i, len(r), m.cols))
+		}
+		copy(m.Row(i), r)
+	}
+	return m
+}
+
+// Dims returns the number of rows and columns.
+func (m Matrix[T]) Dims() (rows, cols int) {
+	return m.rows, m.cols
+}
+
+func (m Matrix[T]) check(i, j int) {
+	if i < 0 || i >= m.rows || j < 0 || j >= m.cols {
+		panic(fmt.Sprintf("Matrix: index (%d, %d) out of range %dx%d", i, j, m.rows, m.cols))
+	}
+}
+
+// At returns element (i, j).
+func (m Matrix[T]) At(i, j int) T {
+	m.check(i, j)
+	return m.data[i*m.stride+j]
+}
+
+// Set sets element (i, j) to v.
+func (m Matrix[T]) Set(i, j int, v T) {
+	m.check(i, j)
+	m.data[i*m.stride+j] = v
+}
+
+// Row returns row i as a slice of the backing array. Its capacity is cut to
+// the row, so appending to it cannot overwrite the next row.
+func (m Matrix[T]) Row(i int) []T {
+	if i < 0 || i >= m.rows {
+		panic(fmt.Sprintf("Matrix: row %d out of range %dx%d", i, m.rows, m.cols))
+	}
+	if m.cols == 0 {
+		// An empty view such as m.View(0, 1, 2, 1) has no storage.
+		return nil
+	}
+	start := i * m.stride
+	return m.data[start : start+m.cols : start+m.cols]
+}
+
+// Col returns column j as a rows×1 view.
+func (m Matrix[T]) Col(j int) Matrix[T] {
+	return m.View(0, j, m.rows, j+1)
+}
+
+// View returns the block of rows [r0, r1) and columns [c0, c1), sharing
+// storage with m, like the slice expression a[low:high].
+func (m Matrix[T]) View(r0, c0, r1, c1 int) Matrix[T] {
+	if r0 < 0 || c0 < 0 || r1 > m.rows || c1 > m.cols || r0 > r1 || c0 > c1 {
+		panic(fmt.Sprintf("Matrix: view [%d:%d, %d:%d] out of range %dx%d", r0, r1, c0, c1, m.rows, m.cols))
+	}
+	v := Matrix[T]{rows: r1 - r0, cols: c1 - c0, stride: m.stride}
+	if v.rows > 0 && v.cols > 0 {
+		start := r0*m.stride + c0
+		end := (r1-1)*m.stride + c1
+		v.data = m.data[start:end:end]
+	}
+	return v
+}
+
+// Clone returns a copy of m with its own storage.
+func (m Matrix[T]) Clone() Matrix[T] {
+	c := NewMatrix[T](m.rows, m.cols)
+	for i := 0; i < m.rows; i++ {
+		copy(c.Row(i), m.Row(i))
+	}
+	return c
+}
+
+// T returns the transpose of m as a new matrix.
+func (m Matrix[T]) T() Matrix[T] {
+	t := NewMatrix[T](m.cols, m.rows)
+	for i := 0; i < m.rows; i++ {
+		for j, v := range m.Row(i) {
+			t.data[j*t.stride+i] = v
+		}
+	}
+	return t
+}
+
+func (m Matrix[T]) sameDims(o Matrix[T], op string) {
+	if m.rows != o.rows || m.cols != o.cols {
+		panic(fmt.Sprintf("Matrix.%s: %dx%d and %dx%d", op, m.rows, m.cols, o.rows, o.cols))
+	}
+}
+
+// Apply returns a new matrix with f applied to every element.
+func (m Matrix[T]) Apply(f func(T) T) Matrix[T] {
+	c := NewMatrix[T](m.rows, m.cols)
+	for i := 0; i < m.rows; i++ {
+		dst := c.Row(i)
+		for j, v := range m.Row(i) {
+			dst[j] = f(v)
+		}
+	}
+	return c
+}
+
+// zip returns a new matrix with f applied to each pair of elements.
+func (m Matrix[T]) zip(o Matrix[T], op string, f func(a, b T) T) Matrix[T] {
+	m.sameDims(o, op)
+	c := NewMatrix[T](m.rows, m.cols)
+	for i := 0; i < m.rows; i++ {
+		dst, b := c.Row(i), o.Row(i)
+		for j, a := range m.Row(i) {
+			dst[j] = f(a, b[j])
+		}
+	}
+	return c
+}
+
+// Add returns m + o.
+func (m Matrix[T]) Add(o Matrix[T]) Matrix[T] {
+	return m.zip(o, "Add", func(a, b T) T { return a + b })
+}
+
+// Sub returns m - o.
+func (m Matrix[T]) Sub(o Matrix[T]) Matrix[T] {
+	return m.zip(o, "Sub", func(a, b T) T { return a - b })
+}
+
+// MulElem returns the elementwise product of m and o.
+func (m Matrix[T]) MulElem(o Matrix[T]) Matrix[T] {
+	return m.zip(o, "MulElem", func(a, b T) T { return a * b })
+}
+
+// Scale returns k·m.
+func (m Matrix[T]) Scale(k T) Matrix[T] {
+	return m.Apply(func(v T) T { return k * v })
+}
+
+// Mul returns the matrix product m·o.
+func (m Matrix[T]) Mul(o Matrix[T]) Matrix[T] {
+	if m.cols != o.rows {
+		panic(fmt.Sprintf("Matrix.Mul: %dx%d times %dx%d", m.rows, m.cols, o.rows, o.cols))
+	}
+	c := NewMatrix[T](m.rows, o.cols)
+	// The i-k-j loop order walks rows of o and c, which are contiguous,
+	// instead of columns of o.
+	for i := 0; i < m.rows; i++ {
+		dst := c.Row(i)
+		for k, a := range m.Row(i) {
+			for j, b := range o.Row(k) {
+				dst[j] += a * b
+			}
+		}
+	}
+	return c
+}
+
+// String formats m with one row per line and aligned columns.
+func (m Matrix[T]) String() string {
+	cells := make([]string, 0, m.rows*m.cols)
+	width := 0
+	for i := 0; i < m.rows; i++ {
+		for _, v := range m.Row(i) {
+			s := fmt.Sprint(v)
+			width = max(width, len(s))
+			cells = append(cells, s)
+		}
+	}
+	var b strings.Builder
+	for i := 0; i < m.rows; i++ {
+		b.WriteString("[")
+		for j := 0; j < m.cols; j++ {
+			if j > 0 {
+				b.WriteString(" ")
+			}
+			fmt.Fprintf(&b, "%*s", width, cells[i*m.cols+j])
+		}
+		b.WriteString("]\n")
+	}
+	return b.String()
+}
+
+func matrixMain() {
+	m := MatrixOf([]int{1, 2, 3}, []int{4, 5, 6})
+	fmt.Print(m)
+	// [1 2 3]
+	// [4 5 6]
+
+	// A view shares storage, like b[5:8] does.
+	v := m.View(0, 1, 2, 3)
+	v.Set(0, 0, 20)
+	m.Col(2).Set(1, 0, 60)
+	fmt.Print(m)
+	// [ 1 20  3]
+	// [ 4  5 60]
+
+	fmt.Print(m.Mul(m.T()))
+	// [ 410  284]
+	// [ 284 3641]
+	fmt.Print(m.Add(m).Scale(-1).View(1, 0, 2, 3))
+	// [  -8  -10 -120]
+
+	// Like b[1:1], a view can be empty.
+	empty := m.View(0, 1, 2, 1)
+	fmt.Println(empty.Dims()) // 2 0
+	fmt.Print(empty.Mul(NewMatrix[int](0, 2)))
+	// [0 0]
+	// [0 0]
+}
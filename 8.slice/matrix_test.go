@@ -0,0 +1,213 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// rows returns the rows of m as fresh slices, for comparing.
+func rows[T Number](m Matrix[T]) [][]T {
+	r, _ := m.Dims()
+	out := make([][]T, r)
+	for i := range out {
+		out[i] = slices.Clone(m.Row(i))
+	}
+	return out
+}
+
+func equalRows[T Number](a, b [][]T) bool {
+	return slices.EqualFunc(a, b, func(x, y []T) bool { return slices.Equal(x, y) })
+}
+
+// panics reports whether f panics with a message containing want.
+func panics(t *testing.T, want string, f func()) {
+	t.Helper()
+	defer func() {
+		r := recover()
+		if s, ok := r.(string); !ok || !strings.Contains(s, want) {
+			t.Errorf("panic %v, want one containing %q", r, want)
+		}
+	}()
+	f()
+}
+
+func TestMatrixView(t *testing.T) {
+	m := MatrixOf([]int{1, 2, 3, 4}, []int{5, 6, 7, 8}, []int{9, 10, 11, 12})
+	v := m.View(1, 1, 3, 3)
+	if got, want := rows(v), [][]int{{6, 7}, {10, 11}}; !equalRows(got, want) {
+		t.Errorf("View = %v, want %v", got, want)
+	}
+
+	// Writes through a view, and through a view of a view, reach m.
+	v.Set(0, 0, 60)
+	v.View(1, 1, 2, 2).Set(0, 0, 110)
+	if m.At(1, 1) != 60 || m.At(2, 2) != 110 {
+		t.Errorf("writes through views did not reach m:\n%v", m)
+	}
+
+	// A row of a view cannot grow into its neighbours.
+	row := v.Row(0)
+	if cap(row) != 2 {
+		t.Errorf("cap(view row) = %d, want 2", cap(row))
+	}
+	_ = append(row, -1)
+	if m.At(1, 3) != 8 {
+		t.Errorf("append to a view row overwrote m: %v", m.Row(1))
+	}
+
+	empty := m.View(0, 2, 3, 2)
+	if r, c := empty.Dims(); r != 3 || c != 0 || empty.Row(1) != nil {
+		t.Errorf("empty view is %dx%d, row %v", r, c, empty.Row(1))
+	}
+
+	panics(t, "view [0:4, 0:1] out of range 3x4", func() { m.View(0, 0, 4, 1) })
+	panics(t, "view [2:1, 0:1]", func() { m.View(2, 0, 1, 1) })
+	panics(t, "index (2, 0) out of range 2x2", func() { v.At(2, 0) })
+}
+
+func TestMatrixCol(t *testing.T) {
+	m := MatrixOf([]int{1, 2}, []int{3, 4}, []int{5, 6})
+	c := m.Col(1)
+	if got, want := rows(c), [][]int{{2}, {4}, {6}}; !equalRows(got, want) {
+		t.Errorf("Col(1) = %v, want %v", got, want)
+	}
+	c.Set(2, 0, 60)
+	if m.At(2, 1) != 60 {
+		t.Error("Col is not a view of m")
+	}
+	panics(t, "out of range", func() { m.Col(2) })
+}
+
+func TestMatrixT(t *testing.T) {
+	m := MatrixOf([]int{1, 2, 3}, []int{4, 5, 6})
+	if got, want := rows(m.T()), [][]int{{1, 4}, {2, 5}, {3, 6}}; !equalRows(got, want) {
+		t.Errorf("T = %v, want %v", got, want)
+	}
+	// The transpose of a view reads only the view's elements.
+	if got, want := rows(m.View(0, 1, 2, 3).T()), [][]int{{2, 5}, {3, 6}}; !equalRows(got, want) {
+		t.Errorf("T of view = %v, want %v", got, want)
+	}
+	// T copies.
+	tr := m.T()
+	tr.Set(0, 0, 100)
+	if m.At(0, 0) != 1 {
+		t.Error("T shares storage with m")
+	}
+}
+
+func TestMatrixMul(t *testing.T) {
+	a := MatrixOf([]int{1, 2, 3}, []int{4, 5, 6})
+	b := MatrixOf([]int{7, 8}, []int{9, 10}, []int{11, 12})
+	if got, want := rows(a.Mul(b)), [][]int{{58, 64}, {139, 154}}; !equalRows(got, want) {
+		t.Errorf("Mul = %v, want %v", got, want)
+	}
+	// Views have a stride larger than their width.
+	big := MatrixOf([]int{0, 1, 2, 0}, []int{0, 3, 4, 0})
+	if got, want := rows(big.View(0, 1, 2, 3).Mul(big.View(0, 1, 2, 3))), [][]int{{7, 10}, {15, 22}}; !equalRows(got, want) {
+		t.Errorf("Mul of views = %v, want %v", got, want)
+	}
+	if got, want := rows(a.View(0, 0, 2, 0).Mul(NewMatrix[int](0, 3))), [][]int{{0, 0, 0}, {0, 0, 0}}; !equalRows(got, want) {
+		t.Errorf("2x0 times 0x3 = %v, want %v", got, want)
+	}
+	panics(t, "Matrix.Mul: 2x3 times 2x3", func() { a.Mul(a) })
+}
+
+func TestMatrixString(t *testing.T) {
+	tests := []struct {
+		m    Matrix[int]
+		want string
+	}{
+		{MatrixOf([]int{1, 2}, []int{3, 4}), "[1 2]\n[3 4]\n"},
+		{MatrixOf([]int{1, -20}, []int{300, 4}), "[  1 -20]\n[300   4]\n"},
+		{NewMatrix[int](2, 0), "[]\n[]\n"},
+		{Matrix[int]{}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.m.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+	if got := MatrixOf([]float64{0.5, 2}).String(); got != "[0.5   2]\n" {
+		t.Errorf("String() of floats = %q", got)
+	}
+}
+
+// Multiplying 128x128 matrices stored in one slice and as a slice of rows.
+
+const benchN = 128
+
+func benchMatrices() (Matrix[float64], [][]float64) {
+	m := NewMatrix[float64](benchN, benchN)
+	ragged := make([][]float64, benchN)
+	for i := range ragged {
+		ragged[i] = make([]float64, benchN)
+		for j := range ragged[i] {
+			m.Set(i, j, float64(i+j))
+			ragged[i][j] = float64(i + j)
+		}
+	}
+	return m, ragged
+}
+
+func BenchmarkMatrixMul(b *testing.B) {
+	m, _ := benchMatrices()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchMatrix = m.Mul(m)
+	}
+}
+
+func BenchmarkRaggedMul(b *testing.B) {
+	_, ragged := benchMatrices()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c := make([][]float64, benchN)
+		for r := range c {
+			c[r] = make([]float64, benchN)
+			for k, a := range ragged[r] {
+				for j, v := range ragged[k] {
+					c[r][j] += a * v
+				}
+			}
+		}
+		benchRagged = c
+	}
+}
+
+func BenchmarkMatrixRowSums(b *testing.B) {
+	m, _ := benchMatrices()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sum := 0.0
+		for r := 0; r < benchN; r++ {
+			for _, v := range m.Row(r) {
+				sum += v
+			}
+		}
+		benchFloat = sum
+	}
+}
+
+func BenchmarkRaggedRowSums(b *testing.B) {
+	_, ragged := benchMatrices()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sum := 0.0
+		for _, row := range ragged {
+			for _, v := range row {
+				sum += v
+			}
+		}
+		benchFloat = sum
+	}
+}
+
+// The sinks keep the compiler from optimising the benchmarked work away.
+var (
+	benchFloat  float64
+	benchMatrix Matrix[float64]
+	benchRagged [][]float64
+)
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Exercise: Slices
+// Implement Pic. It should return a slice of length dy, each element of which
+// is a slice of dx 8-bit unsigned integers. When you run the program, it will
+// display your picture, interpreting the integers as grayscale (well,
+// bluescale) values.
+
+// Pic builds the dy×dx picture whose pixel (x, y) is f(x, y). Values outside
+// 0..255 wrap around, as the conversion uint8(x*y) does.
+func Pic(dx, dy int, f func(x, y float64) float64) [][]uint8 {
+	// Allocate all the rows in one array; each row is a slice of it.
+	pixels := make([]uint8, dx*dy)
+	pic := make([][]uint8, dy)
+	for y := range pic {
+		pic[y] = pixels[y*dx : (y+1)*dx : (y+1)*dx]
+		for x := range pic[y] {
+			v := f(float64(x), float64(y))
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				v = 0
+			}
+			pic[y][x] = uint8(int64(v))
+		}
+	}
+	return pic
+}
+
+// Formulas are the pictures suggested by the Tour. ^ is exclusive or.
+var Formulas = map[string]string{
+	"avg": "(x+y)/2",
+	"mul": "x*y",
+	"xor": "x^y",
+	"log": "x*log(y)",
+}
+
+// PicImage turns a picture into an image with the Tour's colours:
+// value v is shown as the colour (v, v, 255).
+func PicImage(pic [][]uint8) image.Image {
+	dy := len(pic)
+	dx := 0
+	if dy > 0 {
+		dx = len(pic[0])
+	}
+	img := image.NewRGBA(image.Rect(0, 0, dx, dy))
+	for y, row := range pic {
+		for x, v := range row {
+			img.SetRGBA(x, y, color.RGBA{v, v, 255, 255})
+		}
+	}
+	return img
+}
+
+// WritePNG writes a picture to a PNG file.
+func WritePNG(name string, pic [][]uint8) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, PicImage(pic)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ParseFormula compiles an expression in x and y into a function. It accepts
+// numbers, x, y, parentheses, the operators + - * / % ^ & | with Go's
+// precedence, where % ^ & | work on the integer parts, and the functions
+// log, sqrt, sin, cos and abs.
+func ParseFormula(s string) (func(x, y float64) float64, error) {
+	p := &formulaParser{src: s}
+	p.next()
+	f := p.expr()
+	if p.err == nil && p.tok != "" {
+		p.fail("unexpected %q", p.tok)
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return f, nil
+}
+
+type formula func(x, y float64) float64
+
+type formulaParser struct {
+	src string
+	pos int
+	tok string
+	err error
+}
+
+func (p *formulaParser) fail(format string, args ...interface{}) {
+	if p.err == nil {
+		p.err = fmt.Errorf("formula %q: %s", p.src, fmt.Sprintf(format, args...))
+	}
+	p.tok = ""
+}
+
+// next reads the next token: a number, a name, or one operator character.
+func (p *formulaParser) next() {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+	if p.pos == len(p.src) {
+		p.tok = ""
+		return
+	}
+	start := p.pos
+	c := rune(p.src[p.pos])
+	switch {
+	case unicode.IsDigit(c) || c == '.':
+		for p.pos < len(p.src) && (unicode.IsDigit(rune(p.src[p.pos])) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+	case unicode.IsLetter(c):
+		for p.pos < len(p.src) && unicode.IsLetter(rune(p.src[p.pos])) {
+			p.pos++
+		}
+	default:
+		p.pos++
+	}
+	p.tok = p.src[start:p.pos]
+}
+
+func toInt(v float64) int64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0
+	}
+	return int64(v)
+}
+
+// expr parses the additive level: + - ^ |.
+func (p *formulaParser) expr() formula {
+	f := p.term()
+	for p.err == nil {
+		op := p.tok
+		if op != "+" && op != "-" && op != "^" && op != "|" {
+			return f
+		}
+		p.next()
+		l, r := f, p.term()
+		switch op {
+		case "+":
+			f = func(x, y float64) float64 { return l(x, y) + r(x, y) }
+		case "-":
+			f = func(x, y float64) float64 { return l(x, y) - r(x, y) }
+		case "^":
+			f = func(x, y float64) float64 { return float64(toInt(l(x, y)) ^ toInt(r(x, y))) }
+		case "|":
+			f = func(x, y float64) float64 { return float64(toInt(l(x, y)) | toInt(r(x, y))) }
+		}
+	}
+	return f
+}
+
+// term parses the multiplicative level: * / % &.
+func (p *formulaParser) term() formula {
+	f := p.unary()
+	for p.err == nil {
+		op := p.tok
+		if op != "*" && op != "/" && op != "%" && op != "&" {
+			return f
+		}
+		p.next()
+		l, r := f, p.unary()
+		switch op {
+		case "*":
+			f = func(x, y float64) float64 { return l(x, y) * r(x, y) }
+		case "/":
+			f = func(x, y float64) float64 { return l(x, y) / r(x, y) }
+		case "%":
+			f = func(x, y float64) float64 {
+				if d := toInt(r(x, y)); d != 0 {
+					return float64(toInt(l(x, y)) % d)
+				}
+				return 0
+			}
+		case "&":
+			f = func(x, y float64) float64 { return float64(toInt(l(x, y)) & toInt(r(x, y))) }
+		}
+	}
+	return f
+}
+
+var formulaFuncs = map[string]func(float64) float64{
+	"log":  math.Log,
+	"sqrt": math.Sqrt,
+	"sin":  math.Sin,
+	"cos":  math.Cos,
+	"abs":  math.Abs,
+}
+
+func (p *formulaParser) unary() formula {
+	if p.tok == "-" {
+		p.next()
+		f := p.unary()
+		return func(x, y float64) float64 { return -f(x, y) }
+	}
+	return p.primary()
+}
+
+func (p *formulaParser) primary() formula {
+	tok := p.tok
+	switch {
+	case tok == "":
+		p.fail("unexpected end")
+		return nil
+	case tok == "(":
+		p.next()
+		f := p.expr()
+		if p.tok != ")" {
+			p.fail("missing )")
+		}
+		p.next()
+		return f
+	case tok == "x":
+		p.next()
+		return func(x, y float64) float64 { return x }
+	case tok == "y":
+		p.next()
+		return func(x, y float64) float64 { return y }
+	case formulaFuncs[tok] != nil:
+		fn := formulaFuncs[tok]
+		p.next()
+		if p.tok != "(" {
+			p.fail("%s needs (", tok)
+			return nil
+		}
+		arg := p.primary()
+		if arg == nil {
+			return nil
+		}
+		return func(x, y float64) float64 { return fn(arg(x, y)) }
+	}
+	v, err := strconv.ParseFloat(tok, 64)
+	if err != nil {
+		p.fail("unknown %q", tok)
+		return nil
+	}
+	p.next()
+	return func(x, y float64) float64 { return v }
+}
+
+// picCommand implements
+//
+//	go run $(ls *.go | grep -v _test.go) pic [-dx 256] [-dy 256] [-o pic.png] formula
+//
+// The formula is one of the names in Formulas or an expression.
+func picCommand(args []string) error {
+	fs := flag.NewFlagSet("pic", flag.ContinueOnError)
+	dx := fs.Int("dx", 256, "width")
+	dy := fs.Int("dy", 256, "height")
+	out := fs.String("o", "pic.png", "output PNG file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		names := make([]string, 0, len(Formulas))
+		for name, expr := range Formulas {
+			names = append(names, name+" = "+expr)
+		}
+		slices.Sort(names)
+		return errors.New("usage: pic [-dx 256] [-dy 256] [-o pic.png] formula\nformulas: " +
+			strings.Join(names, ", "))
+	}
+	if *dx <= 0 || *dy <= 0 {
+		return errors.New("pic: -dx and -dy must be positive")
+	}
+	src := fs.Arg(0)
+	if expr, ok := Formulas[src]; ok {
+		src = expr
+	}
+	f, err := ParseFormula(src)
+	if err != nil {
+		return err
+	}
+	return WritePNG(*out, Pic(*dx, *dy, f))
+}
+
+func picMain() {
+	xor, _ := ParseFormula(Formulas["xor"])
+	pic := Pic(4, 3, xor)
+	fmt.Println(len(pic), len(pic[0]), pic) // 3 4 [[0 1 2 3] [1 0 3 2] [2 3 0 1]]
+
+	mul, _ := ParseFormula("x*y")
+	big := Pic(256, 256, mul)
+	x, y := 255, 255
+	fmt.Println(big[y][x], uint8(x*y)) // 1 1
+
+	// The PNG decodes back to the same pixels.
+	tmp, err := os.CreateTemp("", "pic-*.png")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+	if err := WritePNG(tmp.Name(), pic); err != nil {
+		fmt.Println(err)
+		return
+	}
+	f, _ := os.Open(tmp.Name())
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	r, _, _, _ := img.At(3, 1).RGBA()
+	fmt.Println(img.Bounds(), r>>8) // (0,0)-(4,3) 2
+
+	_, err = ParseFormula("x*log(")
+	fmt.Println(err) // formula "x*log(": unexpected end
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPicDimensions(t *testing.T) {
+	pic := Pic(5, 3, func(x, y float64) float64 { return x })
+	if len(pic) != 3 {
+		t.Fatalf("Pic(5, 3) has %d rows, want 3", len(pic))
+	}
+	for y, row := range pic {
+		if len(row) != 5 || cap(row) != 5 {
+			t.Errorf("row %d: len %d cap %d, want 5 and 5", y, len(row), cap(row))
+		}
+	}
+	// Rows share one array but appending to one must not overwrite the next.
+	_ = append(pic[0], 99)
+	if pic[1][0] != 0 {
+		t.Errorf("appending to row 0 changed row 1: %v", pic[1])
+	}
+}
+
+func TestPicWraps(t *testing.T) {
+	pic := Pic(1, 1, func(x, y float64) float64 { return 257 })
+	if pic[0][0] != 1 {
+		t.Errorf("257 gives %d, want 1", pic[0][0])
+	}
+	pic = Pic(1, 1, func(x, y float64) float64 { return math.NaN() })
+	if pic[0][0] != 0 {
+		t.Errorf("NaN gives %d, want 0", pic[0][0])
+	}
+}
+
+func TestFormulas(t *testing.T) {
+	tests := []struct {
+		name string
+		x, y int
+		want uint8
+	}{
+		{"avg", 10, 20, 15},
+		{"avg", 255, 255, 255},
+		{"mul", 3, 4, 12},
+		{"mul", 255, 255, 1}, // 65025 wraps to 1, as uint8(x*y) does
+		{"xor", 5, 3, 6},
+		{"xor", 255, 0, 255},
+		{"log", 10, 1, 0},
+		{"log", 10, 100, 46}, // 10·ln 100 = 46.05
+		{"log", 10, 0, 0},    // -Inf is drawn as 0
+	}
+	for _, tt := range tests {
+		f, err := ParseFormula(Formulas[tt.name])
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		pic := Pic(256, 256, f)
+		if got := pic[tt.y][tt.x]; got != tt.want {
+			t.Errorf("%s at (%d, %d) = %d, want %d", tt.name, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestParseFormula(t *testing.T) {
+	tests := []struct {
+		src  string
+		want float64
+	}{
+		{"1+2*3", 7},
+		{"(1+2)*3", 9},
+		{"x-y-1", -3}, // left-associative: (4-6)-1
+		{"-x+10", 6},
+		{"7%4", 3},
+		{"7%0", 0},
+		{"6&3|8", 10},
+		{"2^3*2", 4}, // * binds tighter than ^, as in Go
+		{"sqrt(x*y)", 4.898979485566356},
+		{"abs(y-x)", 2},
+	}
+	for _, tt := range tests {
+		f, err := ParseFormula(tt.src)
+		if err != nil {
+			t.Errorf("ParseFormula(%q): %v", tt.src, err)
+			continue
+		}
+		if got := f(4, 6); got != tt.want {
+			t.Errorf("%s at (4, 6) = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestParseFormulaErrors(t *testing.T) {
+	tests := []struct{ src, msg string }{
+		{"", "unexpected end"},
+		{"x*log(", "unexpected end"},
+		{"(x+y", "missing )"},
+		{"x y", `unexpected "y"`},
+		{"z+1", `unknown "z"`},
+		{"sqrt x", "sqrt needs ("},
+		{"1..2", `unknown "1..2"`},
+	}
+	for _, tt := range tests {
+		_, err := ParseFormula(tt.src)
+		if err == nil || !strings.HasSuffix(err.Error(), tt.msg) {
+			t.Errorf("ParseFormula(%q) error = %v, want one ending in %q", tt.src, err, tt.msg)
+		}
+	}
+}
+
+func TestWritePNG(t *testing.T) {
+	xor, err := ParseFormula(Formulas["xor"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pic := Pic(7, 5, xor)
+	name := filepath.Join(t.TempDir(), "pic.png")
+	if err := WritePNG(name, pic); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 7 || b.Dy() != 5 {
+		t.Fatalf("image is %dx%d, want 7x5", b.Dx(), b.Dy())
+	}
+	for y, row := range pic {
+		for x, v := range row {
+			r, g, b, a := img.At(x, y).RGBA()
+			if r>>8 != uint32(v) || g>>8 != uint32(v) || b>>8 != 255 || a>>8 != 255 {
+				t.Errorf("pixel (%d, %d) = %d %d %d %d, want %d %d 255 255", x, y, r>>8, g>>8, b>>8, a>>8, v, v)
+			}
+		}
+	}
+}
+
+func TestPicCommand(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "out.png")
+	if err := picCommand([]string{"-dx", "8", "-dy", "2", "-o", name, "mul"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(name); err != nil {
+		t.Error(err)
+	}
+	for _, args := range [][]string{
+		{},
+		{"-dx", "0", "xor"},
+		{"-o", name, "x+"},
+	} {
+		if err := picCommand(args); err == nil {
+			t.Errorf("picCommand(%q) succeeded, want an error", args)
+		}
+	}
+}
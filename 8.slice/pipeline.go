@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"strings"
+)
+
+// A slice holds all its elements at once. An iterator, iter.Seq[T], is a
+// function that produces the elements one by one by calling yield, and stops
+// early when yield returns false. The for-range statement accepts one:
+//
+//	for v := range seq { ... }
+//
+// The stages below each take a sequence and return a new one without running
+// it, so a whole pipeline handles one element at a time and never builds the
+// intermediate slices that the eager version would.
+
+// Pair holds two values, for Zip and for map entries.
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+// FromSlice yields the elements of s.
+func FromSlice[T any](s []T) iter.Seq[T] {
+	return slices.Values(s)
+}
+
+// FromMap yields the entries of m, in the map's random order.
+func FromMap[K comparable, V any](m map[K]V) iter.Seq[Pair[K, V]] {
+	return func(yield func(Pair[K, V]) bool) {
+		for k, v := range m {
+			if !yield(Pair[K, V]{k, v}) {
+				return
+			}
+		}
+	}
+}
+
+// FromChan yields the values received from c until it is closed.
+func FromChan[T any](c <-chan T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range c {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Lines yields the lines of r without their line endings. The returned
+// function reports the read error, if any, once the sequence is done.
+func Lines(r io.Reader) (iter.Seq[string], func() error) {
+	var err error
+	seq := func(yield func(string) bool) {
+		sc := bufio.NewScanner(r)
+		for sc.Scan() {
+			if !yield(sc.Text()) {
+				return
+			}
+		}
+		err = sc.Err()
+	}
+	return seq, func() error { return err }
+}
+
+// Map yields f(v) for each v.
+func Map[T, U any](seq iter.Seq[T], f func(T) U) iter.Seq[U] {
+	return func(yield func(U) bool) {
+		for v := range seq {
+			if !yield(f(v)) {
+				return
+			}
+		}
+	}
+}
+
+// Filter yields the values for which keep returns true.
+func Filter[T any](seq iter.Seq[T], keep func(T) bool) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range seq {
+			if keep(v) && !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// TakeWhile yields values until ok returns false for one.
+func TakeWhile[T any](seq iter.Seq[T], ok func(T) bool) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range seq {
+			if !ok(v) || !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Chunk yields consecutive groups of n values; the last may be shorter.
+// The yielded slice is reused for the next chunk: copy it to keep it.
+func Chunk[T any](seq iter.Seq[T], n int) iter.Seq[[]T] {
+	if n <= 0 {
+		panic("Chunk: n must be positive")
+	}
+	return func(yield func([]T) bool) {
+		buf := make([]T, 0, n)
+		for v := range seq {
+			buf = append(buf, v)
+			if len(buf) == n {
+				if !yield(buf) {
+					return
+				}
+				buf = buf[:0]
+			}
+		}
+		if len(buf) > 0 {
+			yield(buf)
+		}
+	}
+}
+
+// Window yields every run of n consecutive values, sliding by one.
+// The yielded slice is reused for the next window: copy it to keep it.
+func Window[T any](seq iter.Seq[T], n int) iter.Seq[[]T] {
+	if n <= 0 {
+		panic("Window: n must be positive")
+	}
+	return func(yield func([]T) bool) {
+		// The buffer holds two windows' worth of values, so each window is a
+		// contiguous slice and values are only moved back once per n steps.
+		buf := make([]T, 0, 2*n)
+		for v := range seq {
+			if len(buf) == cap(buf) {
+				buf = append(buf[:0], buf[len(buf)-n+1:]...)
+			}
+			buf = append(buf, v)
+			if len(buf) >= n && !yield(buf[len(buf)-n:]) {
+				return
+			}
+		}
+	}
+}
+
+// Zip yields pairs of values from a and b, and stops when either ends.
+func Zip[A, B any](a iter.Seq[A], b iter.Seq[B]) iter.Seq[Pair[A, B]] {
+	return func(yield func(Pair[A, B]) bool) {
+		next, stop := iter.Pull(b)
+		defer stop()
+		for va := range a {
+			vb, ok := next()
+			if !ok || !yield(Pair[A, B]{va, vb}) {
+				return
+			}
+		}
+	}
+}
+
+// FlatMap yields every value of the sequences f returns.
+func FlatMap[T, U any](seq iter.Seq[T], f func(T) iter.Seq[U]) iter.Seq[U] {
+	return func(yield func(U) bool) {
+		for v := range seq {
+			for u := range f(v) {
+				if !yield(u) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Dedup drops values equal to the one just before them.
+func Dedup[T comparable](seq iter.Seq[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		var prev T
+		first := true
+		for v := range seq {
+			if !first && v == prev {
+				continue
+			}
+			first, prev = false, v
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Collect runs the pipeline and returns its values in a slice.
+func Collect[T any](seq iter.Seq[T]) []T {
+	return slices.Collect(seq)
+}
+
+// fibonacci sends the first n Fibonacci numbers, as in 16.concurrency.
+func fibonacci(n int, c chan int) {
+	defer close(c)
+	x, y := 0, 1
+	for i := 0; i < n; i++ {
+		c <- x
+		x, y = y, x+y
+	}
+}
+
+func pipelineMain() {
+	evens := Filter(FromSlice([]int{1, 2, 2, 3, 4, 4, 4, 5, 6}), func(v int) bool { return v%2 == 0 })
+	fmt.Println(Collect(Dedup(evens))) // [2 4 6]
+
+	c := make(chan int)
+	go fibonacci(20, c)
+	small := TakeWhile(FromChan(c), func(v int) bool { return v < 30 })
+	for w := range Window(small, 3) {
+		fmt.Print(w, " ") // [0 1 1] [1 1 2] [1 2 3] [2 3 5] [3 5 8] [5 8 13] [8 13 21]
+	}
+	fmt.Println()
+	for range c {
+		// Drain what TakeWhile left, so fibonacci can finish.
+	}
+
+	lines, errf := Lines(strings.NewReader("I Love You!\nI Love Go!"))
+	words := FlatMap(lines, func(l string) iter.Seq[string] { return strings.FieldsSeq(l) })
+	for chunk := range Chunk(words, 4) {
+		fmt.Println(chunk) // [I Love You! I] then [Love Go!]
+	}
+	fmt.Println(errf()) // <nil>
+
+	names := FromSlice([]string{"Tom", "Daniel", "Li Lei"})
+	ages := FromSlice([]int{30, 18})
+	fmt.Println(Collect(Zip(names, ages))) // [{Tom 30} {Daniel 18}]
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"iter"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// take collects the first n values of seq and then stops it, to check that
+// each stage honours an early break.
+func take[T any](seq iter.Seq[T], n int) []T {
+	var out []T
+	for v := range seq {
+		if len(out) == n {
+			break
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// counted yields 1..n and records how many values were asked for.
+func counted(n int, pulled *int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i := 1; i <= n; i++ {
+			*pulled = i
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+// copies collects slices that a stage reuses.
+func copies[T any](seq iter.Seq[[]T]) [][]T {
+	var out [][]T
+	for s := range seq {
+		out = append(out, slices.Clone(s))
+	}
+	return out
+}
+
+func TestMapFilter(t *testing.T) {
+	var pulled int
+	double := Map(counted(10, &pulled), func(v int) int { return 2 * v })
+	if got := Collect(double); !slices.Equal(got, []int{2, 4, 6, 8, 10, 12, 14, 16, 18, 20}) {
+		t.Errorf("Map = %v", got)
+	}
+	if got := take(double, 3); !slices.Equal(got, []int{2, 4, 6}) || pulled != 4 {
+		t.Errorf("Map with break = %v after pulling %d", got, pulled)
+	}
+	odd := Filter(counted(10, &pulled), func(v int) bool { return v%2 == 1 })
+	if got := Collect(odd); !slices.Equal(got, []int{1, 3, 5, 7, 9}) {
+		t.Errorf("Filter = %v", got)
+	}
+	if got := take(odd, 2); !slices.Equal(got, []int{1, 3}) || pulled != 5 {
+		t.Errorf("Filter with break = %v after pulling %d", got, pulled)
+	}
+	if got := Collect(Filter(FromSlice([]int{1, 2}), func(int) bool { return false })); got != nil {
+		t.Errorf("Filter of nothing = %v, want nil", got)
+	}
+}
+
+func TestTakeWhile(t *testing.T) {
+	var pulled int
+	small := TakeWhile(counted(10, &pulled), func(v int) bool { return v < 4 })
+	if got := Collect(small); !slices.Equal(got, []int{1, 2, 3}) || pulled != 4 {
+		t.Errorf("TakeWhile = %v after pulling %d, want [1 2 3] after 4", got, pulled)
+	}
+	if got := take(small, 1); !slices.Equal(got, []int{1}) || pulled != 2 {
+		t.Errorf("TakeWhile with break = %v after pulling %d", got, pulled)
+	}
+}
+
+func TestChunk(t *testing.T) {
+	tests := []struct {
+		n, size int
+		want    [][]int
+	}{
+		{0, 2, nil},
+		{4, 2, [][]int{{1, 2}, {3, 4}}},
+		{5, 2, [][]int{{1, 2}, {3, 4}, {5}}},
+		{2, 5, [][]int{{1, 2}}},
+	}
+	for _, tt := range tests {
+		var pulled int
+		if got := copies(Chunk(counted(tt.n, &pulled), tt.size)); !slices.EqualFunc(got, tt.want, slices.Equal) {
+			t.Errorf("Chunk(1..%d, %d) = %v, want %v", tt.n, tt.size, got, tt.want)
+		}
+	}
+	var pulled int
+	if got := take(Chunk(counted(10, &pulled), 3), 1); len(got) != 1 || pulled != 6 {
+		t.Errorf("Chunk with break = %v after pulling %d", got, pulled)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Chunk(0) did not panic")
+		}
+	}()
+	Chunk(FromSlice([]int{1}), 0)
+}
+
+func TestWindow(t *testing.T) {
+	var pulled int
+	// Long enough for the buffer to be compacted more than once.
+	got := copies(Window(counted(9, &pulled), 3))
+	want := [][]int{{1, 2, 3}, {2, 3, 4}, {3, 4, 5}, {4, 5, 6}, {5, 6, 7}, {6, 7, 8}, {7, 8, 9}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("Window(1..9, 3) = %v", got)
+	}
+	if got := copies(Window(counted(2, &pulled), 3)); got != nil {
+		t.Errorf("Window shorter than n = %v, want nothing", got)
+	}
+	if got := copies(Window(counted(3, &pulled), 1)); !slices.EqualFunc(got, [][]int{{1}, {2}, {3}}, slices.Equal) {
+		t.Errorf("Window(1) = %v", got)
+	}
+	if got := take(Window(counted(9, &pulled), 3), 2); len(got) != 2 || pulled != 5 {
+		t.Errorf("Window with break = %v after pulling %d", got, pulled)
+	}
+}
+
+func TestZip(t *testing.T) {
+	var pulled int
+	got := Collect(Zip(FromSlice([]string{"a", "b", "c"}), counted(2, &pulled)))
+	if want := []Pair[string, int]{{"a", 1}, {"b", 2}}; !slices.Equal(got, want) {
+		t.Errorf("Zip = %v, want %v", got, want)
+	}
+	got = Collect(Zip(FromSlice([]string{"a"}), counted(5, &pulled)))
+	if want := []Pair[string, int]{{"a", 1}}; !slices.Equal(got, want) {
+		t.Errorf("Zip with a short first sequence = %v, want %v", got, want)
+	}
+	if got := take(Zip(counted(5, new(int)), counted(5, &pulled)), 2); len(got) != 2 || pulled > 3 {
+		t.Errorf("Zip with break = %v after pulling %d", got, pulled)
+	}
+}
+
+func TestFlatMap(t *testing.T) {
+	repeat := func(v int) iter.Seq[int] {
+		return func(yield func(int) bool) {
+			for range v {
+				if !yield(v) {
+					return
+				}
+			}
+		}
+	}
+	var pulled int
+	if got := Collect(FlatMap(counted(3, &pulled), repeat)); !slices.Equal(got, []int{1, 2, 2, 3, 3, 3}) {
+		t.Errorf("FlatMap = %v", got)
+	}
+	if got := take(FlatMap(counted(3, &pulled), repeat), 2); !slices.Equal(got, []int{1, 2}) || pulled != 2 {
+		t.Errorf("FlatMap with break = %v after pulling %d", got, pulled)
+	}
+}
+
+func TestDedup(t *testing.T) {
+	if got := Collect(Dedup(FromSlice([]int{0, 0, 1, 1, 1, 0, 2, 2}))); !slices.Equal(got, []int{0, 1, 0, 2}) {
+		t.Errorf("Dedup = %v", got)
+	}
+	// The zero value is not treated as already seen.
+	if got := Collect(Dedup(FromSlice([]string{"", "", "a"}))); !slices.Equal(got, []string{"", "a"}) {
+		t.Errorf("Dedup of empty strings = %q", got)
+	}
+	if got := take(Dedup(FromSlice([]int{1, 1, 2, 3})), 2); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("Dedup with break = %v", got)
+	}
+}
+
+func TestLines(t *testing.T) {
+	seq, errf := Lines(strings.NewReader("one\r\ntwo\n\nthree"))
+	if got := Collect(seq); !slices.Equal(got, []string{"one", "two", "", "three"}) || errf() != nil {
+		t.Errorf("Lines = %q, %v", got, errf())
+	}
+
+	seq, errf = Lines(strings.NewReader("one\ntwo\nthree\n"))
+	if got := take(seq, 1); !slices.Equal(got, []string{"one"}) || errf() != nil {
+		t.Errorf("Lines with break = %q, %v", got, errf())
+	}
+
+	boom := errors.New("boom")
+	seq, errf = Lines(iotest.TimeoutReader(strings.NewReader("one\ntwo\n")))
+	Collect(seq)
+	if errf() == nil {
+		t.Error("Lines hid the read error")
+	}
+	seq, errf = Lines(iotest.ErrReader(boom))
+	if got := Collect(seq); got != nil || !errors.Is(errf(), boom) {
+		t.Errorf("Lines of a failing reader = %q, %v", got, errf())
+	}
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"fmt"
+	"iter"
+	"sync"
+)
+
+// Re-slicing s2 = s2[:3] reuses the capacity after a slice's length instead of
+// allocating. A ring buffer goes further and reuses the space before it too:
+// the elements live in buf[head:] followed by buf[:head], wrapping around, so
+// pushing and popping at either end only moves head and never shifts the
+// other elements.
+
+// FullPolicy says what a Deque does when it is pushed to while full.
+type FullPolicy int
+
+const (
+	// Grow allocates a bigger array, as append would.
+	Grow FullPolicy = iota
+	// Overwrite drops the element at the other end, keeping the last N pushed.
+	Overwrite
+	// Reject leaves the deque unchanged and reports false.
+	Reject
+)
+
+// Deque is a double-ended queue stored in one slice used as a ring.
+// The zero value is an empty deque that grows as needed.
+type Deque[T any] struct {
+	buf    []T
+	head   int // index in buf of the first element
+	n      int // number of elements
+	limit  int // most elements held under Overwrite or Reject
+	policy FullPolicy
+}
+
+// NewDeque returns an empty deque with room for capacity elements and the
+// given behaviour when that room runs out. An Overwrite or Reject deque
+// needs a positive capacity, which is the most it will ever hold.
+func NewDeque[T any](capacity int, policy FullPolicy) *Deque[T] {
+	if capacity < 0 {
+		panic("NewDeque: negative capacity")
+	}
+	if capacity == 0 && policy != Grow {
+		panic("NewDeque: an Overwrite or Reject deque needs a positive capacity")
+	}
+	return &Deque[T]{buf: make([]T, capacity), limit: capacity, policy: policy}
+}
+
+// Len returns the number of elements.
+func (d *Deque[T]) Len() int { return d.n }
+
+// Cap returns the number of elements the deque can hold before its policy
+// applies: the allocated room with Grow, the configured limit otherwise.
+func (d *Deque[T]) Cap() int {
+	if d.policy == Grow {
+		return len(d.buf)
+	}
+	return d.limit
+}
+
+// index maps a logical position, 0 being the front, to an index in buf.
+func (d *Deque[T]) index(i int) int {
+	i += d.head
+	if i >= len(d.buf) {
+		i -= len(d.buf)
+	}
+	return i
+}
+
+// resize moves the elements to a new array of the given size, front first.
+func (d *Deque[T]) resize(size int) {
+	buf := make([]T, size)
+	if d.n > 0 {
+		// The elements are buf[head:] then buf[:head], in at most two copies.
+		k := copy(buf, d.buf[d.head:min(d.head+d.n, len(d.buf))])
+		copy(buf[k:], d.buf[:d.n-k])
+	}
+	d.buf, d.head = buf, 0
+}
+
+// room makes space for one more element and reports whether the push may go
+// ahead. With Overwrite, it drops the element at the end opposite to the push.
+// The array of a bounded deque can be smaller than its limit after Clip; it
+// grows back as needed, but never past the limit.
+func (d *Deque[T]) room(front bool) bool {
+	if d.policy != Grow && d.n >= d.limit {
+		if d.policy == Reject {
+			return false
+		}
+		if front {
+			d.PopBack()
+		} else {
+			d.PopFront()
+		}
+	}
+	if d.n == len(d.buf) {
+		// Doubling keeps pushes amortised O(1).
+		size := max(2*len(d.buf), 4)
+		if d.policy != Grow {
+			size = min(size, d.limit)
+		}
+		d.resize(size)
+	}
+	return true
+}
+
+// PushBack adds v at the back. It reports false if the deque is full and
+// its policy is Reject.
+func (d *Deque[T]) PushBack(v T) bool {
+	if !d.room(false) {
+		return false
+	}
+	d.buf[d.index(d.n)] = v
+	d.n++
+	return true
+}
+
+// PushFront adds v at the front. It reports false if the deque is full and
+// its policy is Reject.
+func (d *Deque[T]) PushFront(v T) bool {
+	if !d.room(true) {
+		return false
+	}
+	d.head--
+	if d.head < 0 {
+		d.head += len(d.buf)
+	}
+	d.buf[d.head] = v
+	d.n++
+	return true
+}
+
+// PopFront removes and returns the front element.
+func (d *Deque[T]) PopFront() (T, bool) {
+	var zero T
+	if d.n == 0 {
+		return zero, false
+	}
+	v := d.buf[d.head]
+	d.buf[d.head] = zero // let the garbage collector have it
+	d.head = d.index(1)
+	d.n--
+	return v, true
+}
+
+// PopBack removes and returns the back element.
+func (d *Deque[T]) PopBack() (T, bool) {
+	var zero T
+	if d.n == 0 {
+		return zero, false
+	}
+	i := d.index(d.n - 1)
+	v := d.buf[i]
+	d.buf[i] = zero
+	d.n--
+	return v, true
+}
+
+// At returns the i-th element from the front.
+func (d *Deque[T]) At(i int) T {
+	if i < 0 || i >= d.n {
+		panic(fmt.Sprintf("Deque: index %d out of range [0:%d]", i, d.n))
+	}
+	return d.buf[d.index(i)]
+}
+
+// Front returns the front element without removing it.
+func (d *Deque[T]) Front() (T, bool) {
+	if d.n == 0 {
+		var zero T
+		return zero, false
+	}
+	return d.buf[d.head], true
+}
+
+// Back returns the back element without removing it.
+func (d *Deque[T]) Back() (T, bool) {
+	if d.n == 0 {
+		var zero T
+		return zero, false
+	}
+	return d.buf[d.index(d.n-1)], true
+}
+
+// All yields the positions and elements from front to back.
+func (d *Deque[T]) All() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		for i := 0; i < d.n; i++ {
+			if !yield(i, d.buf[d.index(i)]) {
+				return
+			}
+		}
+	}
+}
+
+// Backward yields the positions and elements from back to front.
+func (d *Deque[T]) Backward() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		for i := d.n - 1; i >= 0; i-- {
+			if !yield(i, d.buf[d.index(i)]) {
+				return
+			}
+		}
+	}
+}
+
+// Slice returns the elements from front to back in a new slice.
+func (d *Deque[T]) Slice() []T {
+	s := make([]T, 0, d.n)
+	for _, v := range d.All() {
+		s = append(s, v)
+	}
+	return s
+}
+
+// Grow makes room for at least n more elements, like slices.Grow.
+// It also raises the limit of an Overwrite or Reject deque.
+func (d *Deque[T]) Grow(n int) {
+	if n < 0 {
+		panic("Deque.Grow: negative count")
+	}
+	if d.n+n > len(d.buf) {
+		d.resize(d.n + n)
+	}
+	d.limit = max(d.limit, d.n+n)
+}
+
+// Clip frees the unused room, like slices.Clip. The limit of an Overwrite
+// or Reject deque stays as it was.
+func (d *Deque[T]) Clip() {
+	if d.n < len(d.buf) {
+		d.resize(d.n)
+	}
+}
+
+// Clear removes all elements and keeps the capacity.
+func (d *Deque[T]) Clear() {
+	clear(d.buf)
+	d.head, d.n = 0, 0
+}
+
+// SyncDeque is a Deque that is safe to use from several goroutines.
+type SyncDeque[T any] struct {
+	mu sync.Mutex
+	d  Deque[T]
+}
+
+// NewSyncDeque is NewDeque for a SyncDeque.
+func NewSyncDeque[T any](capacity int, policy FullPolicy) *SyncDeque[T] {
+	return &SyncDeque[T]{d: *NewDeque[T](capacity, policy)}
+}
+
+func (s *SyncDeque[T]) PushBack(v T) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.PushBack(v)
+}
+
+func (s *SyncDeque[T]) PushFront(v T) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.PushFront(v)
+}
+
+func (s *SyncDeque[T]) PopFront() (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.PopFront()
+}
+
+func (s *SyncDeque[T]) PopBack() (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.PopBack()
+}
+
+func (s *SyncDeque[T]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.Len()
+}
+
+func (s *SyncDeque[T]) Cap() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.Cap()
+}
+
+func (s *SyncDeque[T]) At(i int) T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.At(i)
+}
+
+func (s *SyncDeque[T]) Front() (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.Front()
+}
+
+func (s *SyncDeque[T]) Back() (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.Back()
+}
+
+func (s *SyncDeque[T]) Grow(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.d.Grow(n)
+}
+
+func (s *SyncDeque[T]) Clip() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.d.Clip()
+}
+
+func (s *SyncDeque[T]) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.d.Clear()
+}
+
+// All yields the elements as they were when the loop started. It iterates
+// over a copy, so the loop body may use the deque without deadlocking.
+func (s *SyncDeque[T]) All() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		snap := s.Slice()
+		for i, v := range snap {
+			if !yield(i, v) {
+				return
+			}
+		}
+	}
+}
+
+// Backward is All from back to front.
+func (s *SyncDeque[T]) Backward() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		snap := s.Slice()
+		for i := len(snap) - 1; i >= 0; i-- {
+			if !yield(i, snap[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Slice returns a copy of the elements from front to back. Iterating over
+// the copy does not hold the lock.
+func (s *SyncDeque[T]) Slice() []T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.Slice()
+}
+
+func ringMain() {
+	// Keep the last 3 log lines.
+	last := NewDeque[string](3, Overwrite)
+	for _, line := range []string{"start", "load", "run", "stop"} {
+		last.PushBack(line)
+	}
+	fmt.Println(last.Slice(), last.Len(), last.Cap()) // [load run stop] 3 3
+
+	var d Deque[int]
+	for i := 1; i <= 5; i++ {
+		d.PushBack(i)
+		d.PushFront(-i)
+	}
+	d.PopBack()
+	fmt.Println(d.Slice(), d.Len(), d.Cap()) // [-5 -4 -3 -2 -1 1 2 3 4] 9 16
+	d.Clip()
+	fmt.Println(d.Len(), d.Cap()) // 9 9
+
+	var wg sync.WaitGroup
+	samples := NewSyncDeque[int](100, Reject)
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			samples.PushBack(i)
+		}()
+	}
+	wg.Wait()
+	fmt.Println(samples.Len()) // 100
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestDequeWraparound(t *testing.T) {
+	// Pushing and popping at both ends moves head all the way round the
+	// array several times; a plain slice gives the expected contents.
+	d := NewDeque[int](5, Grow)
+	var want []int
+	for i := range 40 {
+		switch i % 4 {
+		case 0, 1:
+			d.PushBack(i)
+			want = append(want, i)
+		case 2:
+			d.PushFront(i)
+			want = append([]int{i}, want...)
+		case 3:
+			v, _ := d.PopFront()
+			if v != want[0] {
+				t.Fatalf("step %d: PopFront = %d, want %d", i, v, want[0])
+			}
+			want = want[1:]
+		}
+		if got := d.Slice(); !slices.Equal(got, want) {
+			t.Fatalf("step %d: %v, want %v", i, got, want)
+		}
+	}
+	for i, v := range d.All() {
+		if d.At(i) != v || v != want[i] {
+			t.Errorf("All()[%d] = %d, At = %d, want %d", i, v, d.At(i), want[i])
+		}
+	}
+	var back []int
+	for _, v := range d.Backward() {
+		back = append(back, v)
+	}
+	slices.Reverse(back)
+	if !slices.Equal(back, want) {
+		t.Errorf("Backward = %v, want %v reversed", back, want)
+	}
+	if f, _ := d.Front(); f != want[0] {
+		t.Errorf("Front = %d, want %d", f, want[0])
+	}
+	if b, _ := d.Back(); b != want[len(want)-1] {
+		t.Errorf("Back = %d, want %d", b, want[len(want)-1])
+	}
+}
+
+func TestDequeEmpty(t *testing.T) {
+	var d Deque[string]
+	if _, ok := d.PopFront(); ok {
+		t.Error("PopFront on an empty deque succeeded")
+	}
+	if _, ok := d.PopBack(); ok {
+		t.Error("PopBack on an empty deque succeeded")
+	}
+	if _, ok := d.Front(); ok {
+		t.Error("Front on an empty deque succeeded")
+	}
+	if _, ok := d.Back(); ok {
+		t.Error("Back on an empty deque succeeded")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("At(0) on an empty deque did not panic")
+		}
+	}()
+	d.At(0)
+}
+
+func TestDequeOverwrite(t *testing.T) {
+	d := NewDeque[int](3, Overwrite)
+	for i := 1; i <= 5; i++ {
+		if !d.PushBack(i) {
+			t.Fatalf("PushBack(%d) = false", i)
+		}
+	}
+	if got := d.Slice(); !slices.Equal(got, []int{3, 4, 5}) {
+		t.Errorf("after PushBack 1..5: %v, want [3 4 5]", got)
+	}
+	// Pushing at the front drops the back.
+	d.PushFront(0)
+	if got := d.Slice(); !slices.Equal(got, []int{0, 3, 4}) {
+		t.Errorf("after PushFront(0): %v, want [0 3 4]", got)
+	}
+	if d.Len() != 3 || d.Cap() != 3 {
+		t.Errorf("Len, Cap = %d, %d, want 3, 3", d.Len(), d.Cap())
+	}
+}
+
+func TestDequeReject(t *testing.T) {
+	d := NewDeque[int](2, Reject)
+	if !d.PushBack(1) || !d.PushFront(0) {
+		t.Fatal("push into a deque with room was rejected")
+	}
+	if d.PushBack(2) || d.PushFront(-1) {
+		t.Error("push into a full Reject deque succeeded")
+	}
+	if got := d.Slice(); !slices.Equal(got, []int{0, 1}) {
+		t.Errorf("full Reject deque = %v, want [0 1]", got)
+	}
+	d.PopFront()
+	if !d.PushBack(2) {
+		t.Error("push after a pop was rejected")
+	}
+}
+
+func TestDequeNeedsCapacity(t *testing.T) {
+	for _, policy := range []FullPolicy{Overwrite, Reject} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewDeque(0, %d) did not panic", policy)
+				}
+			}()
+			NewDeque[int](0, policy)
+		}()
+	}
+	d := NewDeque[int](0, Grow)
+	if !d.PushBack(1) || d.Len() != 1 {
+		t.Error("push into NewDeque(0, Grow) failed")
+	}
+}
+
+func TestDequeGrow(t *testing.T) {
+	var d Deque[int]
+	for i := range 5 {
+		d.PushBack(i)
+	}
+	if d.Cap() != 8 {
+		t.Errorf("Cap after 5 pushes = %d, want 8", d.Cap())
+	}
+	d.Grow(10)
+	if d.Cap() < 15 {
+		t.Errorf("Cap after Grow(10) = %d, want at least 15", d.Cap())
+	}
+
+	// Grow raises the limit of a bounded deque.
+	o := NewDeque[int](2, Overwrite)
+	o.PushBack(1)
+	o.PushBack(2)
+	o.Grow(2)
+	for i := 3; i <= 5; i++ {
+		o.PushBack(i)
+	}
+	if got := o.Slice(); !slices.Equal(got, []int{2, 3, 4, 5}) || o.Cap() != 4 {
+		t.Errorf("Overwrite after Grow(2) = %v, cap %d, want [2 3 4 5], cap 4", got, o.Cap())
+	}
+}
+
+func TestDequeClip(t *testing.T) {
+	var d Deque[int]
+	for i := range 5 {
+		d.PushFront(i)
+	}
+	d.Clip()
+	if got := d.Slice(); !slices.Equal(got, []int{4, 3, 2, 1, 0}) || d.Cap() != 5 {
+		t.Errorf("after Clip: %v, cap %d, want [4 3 2 1 0], cap 5", got, d.Cap())
+	}
+
+	// Clipping a bounded deque frees memory but keeps its limit, even when
+	// it is empty.
+	for _, policy := range []FullPolicy{Overwrite, Reject} {
+		b := NewDeque[int](4, policy)
+		b.PushBack(1)
+		b.Clip()
+		b.PopBack()
+		b.Clip()
+		for i := 1; i <= 6; i++ {
+			b.PushBack(i)
+		}
+		want := []int{3, 4, 5, 6}
+		if policy == Reject {
+			want = []int{1, 2, 3, 4}
+		}
+		if got := b.Slice(); !slices.Equal(got, want) || b.Cap() != 4 {
+			t.Errorf("policy %d after Clip: %v, cap %d, want %v, cap 4", policy, got, b.Cap(), want)
+		}
+	}
+}
+
+func TestDequeClear(t *testing.T) {
+	d := NewDeque[int](4, Reject)
+	d.PushBack(1)
+	d.PushBack(2)
+	d.Clear()
+	if d.Len() != 0 || d.Cap() != 4 {
+		t.Errorf("after Clear: len %d, cap %d, want 0, 4", d.Len(), d.Cap())
+	}
+}
+
+func TestSyncDeque(t *testing.T) {
+	s := NewSyncDeque[int](100, Reject)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for i := range 200 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s.PushBack(i) {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if accepted != 100 || s.Len() != 100 || s.Cap() != 100 {
+		t.Fatalf("accepted %d, Len %d, Cap %d, want 100 each", accepted, s.Len(), s.Cap())
+	}
+
+	// Pop concurrently from both ends; every element comes out once.
+	seen := make([]bool, 200)
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				v, ok := s.PopFront()
+				if !ok {
+					v, ok = s.PopBack()
+				}
+				if !ok {
+					return
+				}
+				mu.Lock()
+				if seen[v] {
+					t.Errorf("%d popped twice", v)
+				}
+				seen[v] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if s.Len() != 0 {
+		t.Errorf("Len after popping everything = %d", s.Len())
+	}
+
+	s.Clip()
+	s.Grow(1)
+	s.PushBack(1)
+	s.PushFront(0)
+	s.PushBack(2)
+	if f, _ := s.Front(); f != 0 || s.At(1) != 1 {
+		t.Errorf("Front, At(1) = %d, %d, want 0, 1", f, s.At(1))
+	}
+	if b, _ := s.Back(); b != 2 {
+		t.Errorf("Back = %d, want 2", b)
+	}
+	// The loop body can use the deque.
+	var got []int
+	for _, v := range s.All() {
+		got = append(got, v)
+		s.PushBack(v)
+	}
+	if !slices.Equal(got, []int{0, 1, 2}) || s.Len() != 6 {
+		t.Errorf("All = %v with Len %d after, want [0 1 2] and 6", got, s.Len())
+	}
+	got = got[:0]
+	for _, v := range s.Backward() {
+		got = append(got, v)
+	}
+	if !slices.Equal(got, []int{2, 1, 0, 2, 1, 0}) {
+		t.Errorf("Backward = %v", got)
+	}
+	s.Clear()
+	if s.Len() != 0 {
+		t.Errorf("Len after Clear = %d", s.Len())
+	}
+}
@@ -2,9 +2,18 @@ package main
 
 import (
 	"fmt"
+	"os"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "pic" {
+		if err := picCommand(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// The type [n]T is an array of n values of type T.
 	// An array's length is part of its type, so arrays cannot be resized.
 	var a [2]string
@@ -62,6 +71,7 @@ func main() {
 	// If the backing array of s is too small to fit all the given
 	// values a bigger array will be allocated. The returned slice
 	// will point to the newly allocated array.
+	// (growth/growth.go records how much bigger each new array is.)
 	s3 = append(s3, 1, 2, 3)
 	fmt.Printf("s3: %v &s3: %p\n", s3, s3) // s3: [0 0 0 1 2 3] &s3: 0xc00000a330
 
@@ -83,6 +93,57 @@ func main() {
 	}
 
 	// Slices can contain any type, including other slices.
+
+	aliasMain()
+	// array 1: 10 elements seen
+	//       |______________________________|
+	//   s1  |               =========------| [5:8] len=3 cap=5
+	//   s2  |=========---------------------| [0:3] len=3 cap=10
+	// array 2: 12 elements seen
+	//       |____________________________________|
+	//   s3  |===========================---------| [0:9] len=9 cap=12
+	// s3: grew in place (len 3 -> 6), same backing array
+	// s3: reallocated (cap 6 -> 12), no longer shares the old array
+	// true false
+	// false
+	// array 1: 10 elements seen
+	//      |______________________________|
+	//   x  |=========------               | [0:3] len=3 cap=5
+	//   y  |                  ======------| [6:8] len=2 cap=4
+	//   z  |      ==================------| [2:8] len=6 cap=8
+	// true
+
+	pipelineMain()
+	// [2 4 6]
+	// [0 1 1] [1 1 2] [1 2 3] [2 3 5] [3 5 8] [5 8 13] [8 13 21]
+	// [I Love You! I]
+	// [Love Go!]
+	// <nil>
+	// [{Tom 30} {Daniel 18}]
+
+	matrixMain()
+	// [1 2 3]
+	// [4 5 6]
+	// [ 1 20  3]
+	// [ 4  5 60]
+	// [ 410  284]
+	// [ 284 3641]
+	// [  -8  -10 -120]
+	// 2 0
+	// [0 0]
+	// [0 0]
+
+	ringMain()
+	// [load run stop] 3 3
+	// [-5 -4 -3 -2 -1 1 2 3 4] 9 16
+	// 9 9
+	// 100
+
+	picMain()
+	// 3 4 [[0 1 2 3] [1 0 3 2] [2 3 0 1]]
+	// 1 1
+	// (0,0)-(4,3) 2
+	// formula "x*log(": unexpected end
 }
 
 func printSlice(s string, x []int) {
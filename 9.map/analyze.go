@@ -0,0 +1,468 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// wordCount keys the map by whatever strings.Fields returns, so "You!", "You"
+// and "you" are three different words. An Analyzer reads the text as a stream,
+// splits it into words made of letters, digits and marks, folds case, drops
+// stop words, and keeps one map per kind of count: words, and n-grams of
+// consecutive words.
+
+// ScanTokens is a bufio.SplitFunc that returns each word of the input. A word
+// is a run of letters, digits and marks; an apostrophe between two letters,
+// as in "don't", belongs to the word. Everything else separates words.
+func ScanTokens(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	// Skip separators.
+	start := 0
+	for start < len(data) {
+		r, size := utf8.DecodeRune(data[start:])
+		if r == utf8.RuneError && !atEOF && !utf8.FullRune(data[start:]) {
+			return start, nil, nil
+		}
+		if isWordRune(r) {
+			break
+		}
+		start += size
+	}
+	// Read the word.
+	for i := start; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && !atEOF && !utf8.FullRune(data[i:]) {
+			break // need more input to decide
+		}
+		if isWordRune(r) {
+			i += size
+			continue
+		}
+		if r == '\'' || r == '’' {
+			// Keep the apostrophe only if a letter follows it.
+			next, _ := utf8.DecodeRune(data[i+size:])
+			if i+size == len(data) && !atEOF {
+				break
+			}
+			if unicode.IsLetter(next) {
+				i += size
+				continue
+			}
+		}
+		return i + size, data[start:i], nil
+	}
+	if atEOF && start < len(data) {
+		return len(data), data[start:], nil
+	}
+	// Request more data, keeping the partial word.
+	return start, nil, nil
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+// maxWordSize is the length in bytes past which a run of word runes is not
+// counted. No word is that long; such a run is data, like a line of base64.
+const maxWordSize = 1 << 10
+
+// skipLongWords is ScanTokens, except that a word longer than limit bytes is
+// dropped. A bufio.Scanner fails with bufio.ErrTooLong when a token outgrows
+// its buffer, which would end the whole analysis; here the word is dropped
+// as soon as limit bytes of it are seen, and the rest of it as it arrives.
+func skipLongWords(limit int) bufio.SplitFunc {
+	skipping := false
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if skipping {
+			for i := 0; i < len(data); {
+				r, size := utf8.DecodeRune(data[i:])
+				if r == utf8.RuneError && !atEOF && !utf8.FullRune(data[i:]) {
+					return i, nil, nil
+				}
+				if !isWordRune(r) && r != '\'' && r != '’' {
+					skipping = false
+					return i, nil, nil
+				}
+				i += size
+			}
+			return len(data), nil, nil
+		}
+		advance, token, err := ScanTokens(data, atEOF)
+		if token == nil && err == nil && !atEOF && len(data)-advance > limit {
+			// A partial word already too long.
+			skipping = true
+			return len(data), nil, nil
+		}
+		if len(token) > limit {
+			return advance, nil, nil
+		}
+		return advance, token, err
+	}
+}
+
+// EnglishStopWords are common English words that say little about a text.
+var EnglishStopWords = []string{
+	"a", "an", "and", "are", "as", "at", "be", "but", "by", "for", "from",
+	"has", "have", "he", "her", "his", "i", "in", "is", "it", "its", "me",
+	"my", "not", "of", "on", "or", "our", "she", "so", "that", "the",
+	"their", "them", "they", "this", "to", "was", "we", "were", "will",
+	"with", "you", "your",
+}
+
+// Analyzer counts the words and n-grams of texts. The zero value counts
+// words exactly as they appear, with no stop words and no n-grams. Words
+// longer than 1 KiB are skipped.
+type Analyzer struct {
+	// Fold lower-cases every word, so "You" and "you" are the same word.
+	Fold bool
+	// StopWords are left out of every count. With Fold, list them in lower case.
+	StopWords map[string]bool
+	// NGrams lists the n-gram sizes to count besides single words, such as 2
+	// and 3. N-grams are taken over the words left after dropping stop words.
+	NGrams []int
+	// CJK splits runs of Chinese, Japanese and Korean characters, which
+	// ScanTokens returns as one word, into words. Nil keeps them whole.
+	CJK Segmenter
+}
+
+// StopWordSet builds the set for Analyzer.StopWords.
+func StopWordSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
+
+// Report holds the counts of one or more texts.
+type Report struct {
+	Tokens int                    // number of words counted
+	Words  map[string]int         // count of each distinct word
+	NGrams map[int]map[string]int // n → count of each n-gram, words joined by spaces
+}
+
+// NewReport returns an empty report.
+func NewReport() *Report {
+	return &Report{Words: make(map[string]int), NGrams: make(map[int]map[string]int)}
+}
+
+// Analyze reads r to the end and returns its counts. Only the last few words
+// are kept in memory besides the maps, so r may be much larger than memory.
+func (a *Analyzer) Analyze(r io.Reader) (*Report, error) {
+	rep := NewReport()
+	return rep, a.AnalyzeInto(rep, r)
+}
+
+// AnalyzeInto adds the counts of r to rep.
+func (a *Analyzer) AnalyzeInto(rep *Report, r io.Reader) error {
+	longest := 0
+	for _, n := range a.NGrams {
+		if n < 2 {
+			return fmt.Errorf("analyze: n-gram size %d, want 2 or more", n)
+		}
+		longest = max(longest, n)
+		if rep.NGrams[n] == nil {
+			rep.NGrams[n] = make(map[string]int)
+		}
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Split(skipLongWords(maxWordSize))
+	// recent holds the last few words, for the n-grams ending at the
+	// current word.
+	recent := make([]string, 0, longest)
+	add := func(w string) {
+		if a.Fold {
+			w = strings.ToLower(w)
+		}
+		if a.StopWords[w] {
+			return
+		}
+		rep.Tokens++
+		rep.Words[w]++
+
+		if longest == 0 {
+			return
+		}
+		if len(recent) == longest {
+			recent = append(recent[:0], recent[1:]...)
+		}
+		recent = append(recent, w)
+		for _, n := range a.NGrams {
+			if len(recent) >= n {
+				rep.NGrams[n][strings.Join(recent[len(recent)-n:], " ")]++
+			}
+		}
+	}
+	for sc.Scan() {
+		if a.CJK == nil {
+			add(sc.Text())
+			continue
+		}
+		for _, w := range segment(sc.Text(), a.CJK) {
+			add(w)
+		}
+	}
+	return sc.Err()
+}
+
+// Merge adds the counts of o to rep.
+func (rep *Report) Merge(o *Report) {
+	rep.Tokens += o.Tokens
+	for w, c := range o.Words {
+		rep.Words[w] += c
+	}
+	for n, grams := range o.NGrams {
+		if rep.NGrams[n] == nil {
+			rep.NGrams[n] = make(map[string]int)
+		}
+		for g, c := range grams {
+			rep.NGrams[n][g] += c
+		}
+	}
+}
+
+// TypeTokenRatio returns the number of distinct words divided by the number
+// of words: 1 when no word repeats, near 0 when a few words repeat a lot.
+func (rep *Report) TypeTokenRatio() float64 {
+	if rep.Tokens == 0 {
+		return 0
+	}
+	return float64(len(rep.Words)) / float64(rep.Tokens)
+}
+
+// WordCount is one entry of a top-N list.
+type WordCount struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+// Top returns the n most frequent entries of counts, most frequent first and
+// ties in alphabetical order. n <= 0 returns all of them.
+func Top(counts map[string]int, n int) []WordCount {
+	top := make([]WordCount, 0, len(counts))
+	for w, c := range counts {
+		top = append(top, WordCount{w, c})
+	}
+	slices.SortFunc(top, func(a, b WordCount) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Word, b.Word)
+	})
+	if n > 0 && n < len(top) {
+		top = top[:n]
+	}
+	return top
+}
+
+// ngramSizes returns the n-gram sizes in the report in increasing order.
+func (rep *Report) ngramSizes() []int {
+	sizes := make([]int, 0, len(rep.NGrams))
+	for n := range rep.NGrams {
+		sizes = append(sizes, n)
+	}
+	slices.Sort(sizes)
+	return sizes
+}
+
+// WriteText writes a readable summary with the top n words and n-grams.
+func (rep *Report) WriteText(w io.Writer, n int) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "tokens: %d\ntypes: %d\ntype/token ratio: %.3f\n",
+		rep.Tokens, len(rep.Words), rep.TypeTokenRatio())
+	writeTop := func(title string, top []WordCount) {
+		fmt.Fprintf(bw, "\n%s\n", title)
+		width := 0
+		for _, e := range top {
+			width = max(width, utf8.RuneCountInString(e.Word))
+		}
+		for _, e := range top {
+			pad := width - utf8.RuneCountInString(e.Word)
+			fmt.Fprintf(bw, "  %s%s  %d\n", e.Word, strings.Repeat(" ", pad), e.Count)
+		}
+	}
+	writeTop("words", Top(rep.Words, n))
+	for _, size := range rep.ngramSizes() {
+		writeTop(strconv.Itoa(size)+"-grams", Top(rep.NGrams[size], n))
+	}
+	return bw.Flush()
+}
+
+// WriteCSV writes the top n words and n-grams as rows of n,term,count, where
+// n is 1 for single words.
+func (rep *Report) WriteCSV(w io.Writer, n int) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"n", "term", "count"})
+	for _, e := range Top(rep.Words, n) {
+		cw.Write([]string{"1", e.Word, strconv.Itoa(e.Count)})
+	}
+	for _, size := range rep.ngramSizes() {
+		for _, e := range Top(rep.NGrams[size], n) {
+			cw.Write([]string{strconv.Itoa(size), e.Word, strconv.Itoa(e.Count)})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the statistics and the top n words and n-grams as one
+// JSON object.
+func (rep *Report) WriteJSON(w io.Writer, n int) error {
+	out := struct {
+		Tokens         int                    `json:"tokens"`
+		Types          int                    `json:"types"`
+		TypeTokenRatio float64                `json:"typeTokenRatio"`
+		Words          []WordCount            `json:"words"`
+		NGrams         map[string][]WordCount `json:"ngrams,omitempty"`
+	}{
+		Tokens:         rep.Tokens,
+		Types:          len(rep.Words),
+		TypeTokenRatio: rep.TypeTokenRatio(),
+		Words:          Top(rep.Words, n),
+	}
+	if len(rep.NGrams) > 0 {
+		out.NGrams = make(map[string][]WordCount)
+		for size, grams := range rep.NGrams {
+			out.NGrams[strconv.Itoa(size)] = Top(grams, n)
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// Write writes the report in the given format: text, csv or json.
+func (rep *Report) Write(w io.Writer, format string, n int) error {
+	switch format {
+	case "text":
+		return rep.WriteText(w, n)
+	case "csv":
+		return rep.WriteCSV(w, n)
+	case "json":
+		return rep.WriteJSON(w, n)
+	}
+	return fmt.Errorf("unknown format %q, want text, csv or json", format)
+}
+
+// analyzeFlags are the Analyzer options shared by the commands that count
+// words.
+type analyzeFlags struct {
+	fold   *bool
+	stop   *string
+	ngrams *string
+	cjk    *string
+	top    *int
+	format *string
+}
+
+func addAnalyzeFlags(fs *flag.FlagSet) analyzeFlags {
+	return analyzeFlags{
+		fold:   fs.Bool("fold", true, "fold words to lower case"),
+		stop:   fs.String("stop", "", `stop words: "en" for the built-in English list, or a file with one word per line`),
+		ngrams: fs.String("ngrams", "", "comma-separated n-gram sizes to count, such as 2,3"),
+		cjk:    fs.String("cjk", "", "split Chinese, Japanese and Korean text: unigram, bigram, both, or dict=FILE"),
+		top:    fs.Int("top", 10, "number of entries in each list; 0 for all"),
+		format: fs.String("format", "text", "output format: text, csv or json"),
+	}
+}
+
+func (f analyzeFlags) analyzer() (*Analyzer, error) {
+	a := &Analyzer{Fold: *f.fold}
+	switch *f.stop {
+	case "":
+	case "en":
+		a.StopWords = StopWordSet(EnglishStopWords...)
+	default:
+		data, err := os.ReadFile(*f.stop)
+		if err != nil {
+			return nil, err
+		}
+		words := strings.Fields(string(data))
+		if a.Fold {
+			for i, w := range words {
+				words[i] = strings.ToLower(w)
+			}
+		}
+		a.StopWords = StopWordSet(words...)
+	}
+	seg, err := parseSegmenter(*f.cjk)
+	if err != nil {
+		return nil, err
+	}
+	a.CJK = seg
+	if *f.ngrams != "" {
+		for _, s := range strings.Split(*f.ngrams, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil || n < 2 {
+				return nil, fmt.Errorf("bad n-gram size %q", s)
+			}
+			a.NGrams = append(a.NGrams, n)
+		}
+	}
+	return a, nil
+}
+
+// analyzeCommand implements:
+//
+//	go run 9.map/*.go analyze [-fold] [-stop en|file] [-ngrams 2,3] [-top 10] [-format text|csv|json] [file ...]
+//
+// It reads standard input when no file is given.
+func analyzeCommand(args []string) error {
+	fs := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	flags := addAnalyzeFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *flags.top < 0 {
+		return errors.New("-top must not be negative")
+	}
+	a, err := flags.analyzer()
+	if err != nil {
+		return err
+	}
+	rep := NewReport()
+	if fs.NArg() == 0 {
+		if err := a.AnalyzeInto(rep, os.Stdin); err != nil {
+			return err
+		}
+	}
+	for _, name := range fs.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = a.AnalyzeInto(rep, f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return rep.Write(os.Stdout, *flags.format, *flags.top)
+}
+
+func analyzeMain() {
+	a := &Analyzer{Fold: true, NGrams: []int{2}}
+	rep, _ := a.Analyze(strings.NewReader("I Love You! I Love You! I Love You!"))
+	fmt.Println(rep.Words)                                        // map[i:3 love:3 you:3]
+	fmt.Println(rep.Tokens, len(rep.Words), rep.TypeTokenRatio()) // 9 3 0.3333333333333333
+	fmt.Println(Top(rep.NGrams[2], 2))                            // [{i love 3} {love you 3}]
+
+	a = &Analyzer{Fold: true, StopWords: StopWordSet(EnglishStopWords...)}
+	rep, _ = a.Analyze(strings.NewReader("Don't panic: the disk isn't full, the DISK is 'slow'."))
+	fmt.Println(Top(rep.Words, 3)) // [{disk 2} {don't 1} {full 1}]
+	rep.WriteCSV(os.Stdout, 1)
+	// n,term,count
+	// 1,disk,2
+}
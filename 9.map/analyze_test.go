@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// scanAll splits the text read from r with split.
+func scanAll(t *testing.T, r io.Reader, split bufio.SplitFunc) []string {
+	t.Helper()
+	sc := bufio.NewScanner(r)
+	sc.Split(split)
+	var words []string
+	for sc.Scan() {
+		words = append(words, sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return words
+}
+
+func TestScanTokens(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{" -- ... !", nil},
+		{"I Love You!", []string{"I", "Love", "You"}},
+		{"Don't panic: the disk isn't full, the DISK is 'slow'.",
+			[]string{"Don't", "panic", "the", "disk", "isn't", "full", "the", "DISK", "is", "slow"}},
+		{"rock’n’roll in 1969x", []string{"rock’n’roll", "in", "1969x"}},
+		{"James' cat's", []string{"James", "cat's"}},
+		{"cafe\u0301 naïve", []string{"cafe\u0301", "naïve"}},
+		{"日本語とGo", []string{"日本語とGo"}},
+		{"end'", []string{"end"}},
+	}
+	for _, tt := range tests {
+		if got := scanAll(t, strings.NewReader(tt.in), ScanTokens); !slices.Equal(got, tt.want) {
+			t.Errorf("ScanTokens(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		// Reading a byte at a time splits runes and apostrophes across calls.
+		if got := scanAll(t, iotest.OneByteReader(strings.NewReader(tt.in)), ScanTokens); !slices.Equal(got, tt.want) {
+			t.Errorf("ScanTokens(%q) a byte at a time = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeLongWords(t *testing.T) {
+	long := strings.Repeat("é", bufio.MaxScanTokenSize)
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"a " + long + " b", []string{"a", "b"}},
+		{"a " + long + "'s b", []string{"a", "b"}},
+		{"a " + long, []string{"a"}},
+		{"a " + strings.Repeat("x", maxWordSize) + " b", []string{"a", strings.Repeat("x", maxWordSize), "b"}},
+		{"a " + strings.Repeat("x", maxWordSize+1) + " b", []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		for _, r := range []io.Reader{strings.NewReader(tt.in), iotest.HalfReader(strings.NewReader(tt.in))} {
+			if got := scanAll(t, r, skipLongWords(maxWordSize)); !slices.Equal(got, tt.want) {
+				t.Errorf("skipLongWords(%.20q...) = %d words, want %d", tt.in, len(got), len(tt.want))
+			}
+		}
+	}
+
+	// A word longer than a Scanner's buffer does not stop the count.
+	rep, err := (&Analyzer{}).Analyze(strings.NewReader("one " + long + " two"))
+	if err != nil || rep.Tokens != 2 {
+		t.Errorf("Analyze with a long word = %d tokens, %v, want 2, nil", rep.Tokens, err)
+	}
+}
+
+func TestAnalyzeFold(t *testing.T) {
+	text := "You! you YOU, Ünïcode ünïcode"
+	rep, err := (&Analyzer{}).Analyze(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rep.Words) != 5 || rep.Tokens != 5 {
+		t.Errorf("without Fold: %v", rep.Words)
+	}
+	rep, _ = (&Analyzer{Fold: true}).Analyze(strings.NewReader(text))
+	if rep.Words["you"] != 3 || rep.Words["ünïcode"] != 2 || len(rep.Words) != 2 {
+		t.Errorf("with Fold: %v", rep.Words)
+	}
+
+	// Stop words are matched after folding.
+	a := &Analyzer{Fold: true, StopWords: StopWordSet("the", "a")}
+	rep, _ = a.Analyze(strings.NewReader("The cat and A dog"))
+	if want := map[string]int{"cat": 1, "and": 1, "dog": 1}; !maps.Equal(rep.Words, want) || rep.Tokens != 3 {
+		t.Errorf("with stop words: %v, want %v", rep.Words, want)
+	}
+}
+
+func TestAnalyzeNGrams(t *testing.T) {
+	a := &Analyzer{Fold: true, StopWords: StopWordSet("the"), NGrams: []int{3, 2}}
+	rep, err := a.Analyze(strings.NewReader("The cat saw the dog saw the cat saw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The n-grams run over "cat saw dog saw cat saw", with "the" gone.
+	if want := map[string]int{"cat saw": 2, "saw dog": 1, "dog saw": 1, "saw cat": 1}; !maps.Equal(rep.NGrams[2], want) {
+		t.Errorf("2-grams = %v, want %v", rep.NGrams[2], want)
+	}
+	if want := map[string]int{"cat saw dog": 1, "saw dog saw": 1, "dog saw cat": 1, "saw cat saw": 1}; !maps.Equal(rep.NGrams[3], want) {
+		t.Errorf("3-grams = %v, want %v", rep.NGrams[3], want)
+	}
+
+	// Counts carry on across texts, but n-grams do not join them.
+	more := NewReport()
+	a.AnalyzeInto(more, strings.NewReader("cat"))
+	a.AnalyzeInto(more, strings.NewReader("saw"))
+	if len(more.NGrams[2]) != 0 || more.Tokens != 2 {
+		t.Errorf("n-grams across texts: %v", more.NGrams[2])
+	}
+	rep.Merge(more)
+	if rep.Tokens != 8 || rep.Words["cat"] != 3 || rep.NGrams[2]["cat saw"] != 2 {
+		t.Errorf("after Merge: %d tokens, %v", rep.Tokens, rep.Words)
+	}
+
+	if _, err := (&Analyzer{NGrams: []int{1}}).Analyze(strings.NewReader("x")); err == nil {
+		t.Error("an n-gram size of 1 was accepted")
+	}
+}
+
+func TestTop(t *testing.T) {
+	counts := map[string]int{"b": 2, "a": 2, "c": 3, "d": 1}
+	if got, want := Top(counts, 3), []WordCount{{"c", 3}, {"a", 2}, {"b", 2}}; !slices.Equal(got, want) {
+		t.Errorf("Top(3) = %v, want %v", got, want)
+	}
+	if got := Top(counts, 0); len(got) != 4 {
+		t.Errorf("Top(0) = %v, want all 4", got)
+	}
+}
+
+func TestReportWrite(t *testing.T) {
+	a := &Analyzer{Fold: true, NGrams: []int{2}}
+	rep, err := a.Analyze(strings.NewReader("the cat saw the cat. The dog saw the cat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		format, want string
+	}{
+		{"text", `tokens: 10
+types: 4
+type/token ratio: 0.400
+
+words
+  the  4
+  cat  3
+
+2-grams
+  the cat  3
+  saw the  2
+`},
+		{"csv", `n,term,count
+1,the,4
+1,cat,3
+2,the cat,3
+2,saw the,2
+`},
+		{"json", `{
+  "tokens": 10,
+  "types": 4,
+  "typeTokenRatio": 0.4,
+  "words": [
+    {
+      "word": "the",
+      "count": 4
+    },
+    {
+      "word": "cat",
+      "count": 3
+    }
+  ],
+  "ngrams": {
+    "2": [
+      {
+        "word": "the cat",
+        "count": 3
+      },
+      {
+        "word": "saw the",
+        "count": 2
+      }
+    ]
+  }
+}
+`},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		if err := rep.Write(&b, tt.format, 2); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != tt.want {
+			t.Errorf("format %s:\n%s\nwant:\n%s", tt.format, b.String(), tt.want)
+		}
+	}
+	if err := rep.Write(io.Discard, "xml", 2); err == nil {
+		t.Error("format xml was accepted")
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// The benchmarks count the words of a text, as wordCount does, with each kind
+// of map; cd 9.map && go test -bench . -benchmem *.go runs them. The words
+// follow Zipf's law, as in real text, so a few words are very frequent and
+// most are rare.
+
+const benchTextLen = 200000
+
+var benchWords = func() []string {
+	const vocabulary = 20000
+	r := rand.New(rand.NewPCG(1, 2))
+	zipf := rand.NewZipf(r, 1.1, 1, vocabulary-1)
+	words := make([]string, benchTextLen)
+	for i := range words {
+		words[i] = "w" + strconv.FormatUint(zipf.Uint64(), 10)
+	}
+	return words
+}()
+
+// reportPerWord adds the time per word counted to the benchmark's output.
+func reportPerWord(b *testing.B) {
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N)/benchTextLen, "ns/word")
+}
+
+func BenchmarkMap(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m := make(map[string]int)
+		for _, w := range benchWords {
+			m[w]++
+		}
+		benchSink = len(m)
+	}
+	reportPerWord(b)
+}
+
+func BenchmarkHashMap(b *testing.B) {
+	for _, load := range []float64{0.5, 0.875, 0.95} {
+		b.Run(fmt.Sprintf("load=%.3g", load), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				m := NewHashMap[string, int](StringHash, Equal, HashMapOptions{MaxLoad: load})
+				for _, w := range benchWords {
+					m.Update(w, func(n int, _ bool) int { return n + 1 })
+				}
+				benchSink = m.Len()
+			}
+			reportPerWord(b)
+		})
+	}
+}
+
+func BenchmarkSyncMap(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var m sync.Map
+		for _, w := range benchWords {
+			countSyncMap(&m, w)
+		}
+	}
+	reportPerWord(b)
+}
+
+// countParallel splits the text between GOMAXPROCS goroutines and calls
+// count with each part.
+func countParallel(count func(part []string)) {
+	workers := runtime.GOMAXPROCS(0)
+	var wg sync.WaitGroup
+	size := (len(benchWords) + workers - 1) / workers
+	for start := 0; start < len(benchWords); start += size {
+		part := benchWords[start:min(start+size, len(benchWords))]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			count(part)
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkMapMutexParallel(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var mu sync.Mutex
+		m := make(map[string]int)
+		countParallel(func(part []string) {
+			for _, w := range part {
+				mu.Lock()
+				m[w]++
+				mu.Unlock()
+			}
+		})
+		benchSink = len(m)
+	}
+	reportPerWord(b)
+}
+
+func BenchmarkSyncMapParallel(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var m sync.Map
+		countParallel(func(part []string) {
+			for _, w := range part {
+				countSyncMap(&m, w)
+			}
+		})
+	}
+	reportPerWord(b)
+}
+
+// BenchmarkHashMapMergeParallel gives each goroutine its own map and merges
+// them at the end, as CountTree does.
+func BenchmarkHashMapMergeParallel(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var mu sync.Mutex
+		total := NewHashMap[string, int](StringHash, Equal, HashMapOptions{})
+		countParallel(func(part []string) {
+			m := NewHashMap[string, int](StringHash, Equal, HashMapOptions{})
+			for _, w := range part {
+				m.Update(w, func(n int, _ bool) int { return n + 1 })
+			}
+			mu.Lock()
+			for w, n := range m.All() {
+				total.Update(w, func(old int, _ bool) int { return old + n })
+			}
+			mu.Unlock()
+		})
+		benchSink = total.Len()
+	}
+	reportPerWord(b)
+}
+
+// countSyncMap adds one to the count of w. The counts are *atomic.Int64, so
+// only the first sighting of a word stores into the sync.Map.
+func countSyncMap(m *sync.Map, w string) {
+	c, ok := m.Load(w)
+	if !ok {
+		c, _ = m.LoadOrStore(w, new(atomic.Int64))
+	}
+	c.(*atomic.Int64).Add(1)
+}
+
+// TestCountSyncMap checks the parallel sync.Map count against a plain map.
+func TestCountSyncMap(t *testing.T) {
+	want := make(map[string]int)
+	for _, w := range benchWords {
+		want[w]++
+	}
+	var m sync.Map
+	countParallel(func(part []string) {
+		for _, w := range part {
+			countSyncMap(&m, w)
+		}
+	})
+	n := 0
+	m.Range(func(k, v any) bool {
+		n++
+		if got := v.(*atomic.Int64).Load(); got != int64(want[k.(string)]) {
+			t.Errorf("%s counted %d times, want %d", k, got, want[k.(string)])
+		}
+		return true
+	})
+	if n != len(want) {
+		t.Errorf("sync.Map has %d words, want %d", n, len(want))
+	}
+}
+
+// Each benchmark stores its result in benchSink, so the counting cannot be
+// optimised away.
+var benchSink int
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// A map used as a cache grows forever: every v, ok := m[key] that misses is
+// followed by m[key] = v, and nothing is ever deleted. Cache bounds the number
+// of entries, or their total weight, and evicts an entry to make room:
+//
+//   - LRU evicts the least recently used entry. The entries are kept in an
+//     OrderedMap from least to most recently used; a hit moves its key to the
+//     back.
+//   - LFU evicts the least frequently used entry, the least recently used one
+//     among those used equally often. Keys are kept in one OrderedMap per use
+//     count, and the smallest count is tracked, so both hits and evictions are
+//     O(1).
+
+// CachePolicy picks the entry a Cache evicts.
+type CachePolicy int
+
+const (
+	LRU CachePolicy = iota
+	LFU
+)
+
+func (p CachePolicy) String() string {
+	if p == LFU {
+		return "LFU"
+	}
+	return "LRU"
+}
+
+// CacheOptions configures a Cache. At least one of MaxEntries and MaxWeight
+// must be set.
+type CacheOptions[K comparable, V any] struct {
+	Policy     CachePolicy
+	MaxEntries int // 0 means no limit on the number of entries
+	// MaxWeight limits the sum of Weight over all entries, such as their size
+	// in bytes. 0 means no limit.
+	MaxWeight int64
+	// Weight returns the weight of an entry. Nil weighs every entry 1.
+	Weight func(K, V) int64
+	// OnEvict is called for each entry evicted to make room, not for entries
+	// removed by Delete or replaced by Set.
+	OnEvict func(K, V)
+}
+
+// CacheStats counts what happened to a Cache.
+type CacheStats struct {
+	Hits, Misses, Evictions int
+}
+
+// HitRate returns the fraction of lookups that were hits.
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Cache is a bounded map with LRU or LFU eviction. It is not safe for
+// concurrent use; see SyncCache.
+type Cache[K comparable, V any] struct {
+	opts    CacheOptions[K, V]
+	entries map[K]*cacheEntry[V]
+	weight  int64
+	stats   CacheStats
+
+	recency *OrderedMap[K, struct{}]         // LRU: least recently used first
+	freqs   map[int]*OrderedMap[K, struct{}] // LFU: use count → keys, oldest first
+	minFreq int
+}
+
+type cacheEntry[V any] struct {
+	value  V
+	weight int64
+	freq   int
+}
+
+// NewCache returns an empty cache.
+func NewCache[K comparable, V any](opts CacheOptions[K, V]) *Cache[K, V] {
+	if opts.MaxEntries <= 0 && opts.MaxWeight <= 0 {
+		panic("NewCache: MaxEntries or MaxWeight must be set")
+	}
+	c := &Cache[K, V]{opts: opts, entries: make(map[K]*cacheEntry[V])}
+	if opts.Policy == LFU {
+		c.freqs = make(map[int]*OrderedMap[K, struct{}])
+	} else {
+		c.recency = NewOrderedMap[K, struct{}]()
+	}
+	return c
+}
+
+// Get returns the value for key and whether it is present, and counts the
+// lookup as a use of the entry.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	e, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+	c.stats.Hits++
+	c.touch(key, e)
+	return e.value, true
+}
+
+// Peek is Get without counting a use or a hit.
+func (c *Cache[K, V]) Peek(key K) (V, bool) {
+	e, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// Set adds or replaces the entry for key, which counts as a use, and evicts
+// entries until the cache is within its limits again. An entry heavier than
+// MaxWeight is evicted at once, after everything else.
+func (c *Cache[K, V]) Set(key K, value V) {
+	w := int64(1)
+	if c.opts.Weight != nil {
+		w = c.opts.Weight(key, value)
+	}
+	if e, ok := c.entries[key]; ok {
+		c.weight += w - e.weight
+		e.value, e.weight = value, w
+		c.touch(key, e)
+	} else {
+		// Make room first, so the new entry is not the one evicted.
+		for len(c.entries) > 0 && c.full(1, w) {
+			c.evict()
+		}
+		e := &cacheEntry[V]{value: value, weight: w}
+		c.entries[key] = e
+		c.weight += w
+		c.link(key, e)
+	}
+	for c.full(0, 0) {
+		c.evict()
+	}
+}
+
+// Delete removes key and reports whether it was present.
+func (c *Cache[K, V]) Delete(key K) bool {
+	e, ok := c.entries[key]
+	if ok {
+		c.remove(key, e)
+	}
+	return ok
+}
+
+// Len returns the number of entries.
+func (c *Cache[K, V]) Len() int { return len(c.entries) }
+
+// Weight returns the total weight of the entries.
+func (c *Cache[K, V]) Weight() int64 { return c.weight }
+
+// Stats returns the hit, miss and eviction counts so far.
+func (c *Cache[K, V]) Stats() CacheStats { return c.stats }
+
+// full reports whether adding n entries weighing w would exceed a limit.
+func (c *Cache[K, V]) full(n int, w int64) bool {
+	return c.opts.MaxEntries > 0 && len(c.entries)+n > c.opts.MaxEntries ||
+		c.opts.MaxWeight > 0 && c.weight+w > c.opts.MaxWeight
+}
+
+// link records a new entry as just used.
+func (c *Cache[K, V]) link(key K, e *cacheEntry[V]) {
+	if c.recency != nil {
+		c.recency.Set(key, struct{}{})
+		return
+	}
+	e.freq = 1
+	c.minFreq = 1
+	c.bucket(1).Set(key, struct{}{})
+}
+
+// touch records a use of an existing entry.
+func (c *Cache[K, V]) touch(key K, e *cacheEntry[V]) {
+	if c.recency != nil {
+		c.recency.MoveToBack(key)
+		return
+	}
+	old := c.freqs[e.freq]
+	old.Delete(key)
+	if old.Len() == 0 {
+		delete(c.freqs, e.freq)
+		if c.minFreq == e.freq {
+			c.minFreq++
+		}
+	}
+	e.freq++
+	c.bucket(e.freq).Set(key, struct{}{})
+}
+
+func (c *Cache[K, V]) bucket(freq int) *OrderedMap[K, struct{}] {
+	b := c.freqs[freq]
+	if b == nil {
+		b = NewOrderedMap[K, struct{}]()
+		c.freqs[freq] = b
+	}
+	return b
+}
+
+func (c *Cache[K, V]) remove(key K, e *cacheEntry[V]) {
+	delete(c.entries, key)
+	c.weight -= e.weight
+	if c.recency != nil {
+		c.recency.Delete(key)
+		return
+	}
+	b := c.freqs[e.freq]
+	b.Delete(key)
+	if b.Len() == 0 {
+		delete(c.freqs, e.freq)
+		if c.minFreq == e.freq && len(c.freqs) > 0 {
+			// Rare: only Delete and evict can empty the lowest bucket, and
+			// then the next count up is not known without a search.
+			c.minFreq = 0
+			for f := range c.freqs {
+				if c.minFreq == 0 || f < c.minFreq {
+					c.minFreq = f
+				}
+			}
+		}
+	}
+}
+
+// evict removes the entry the policy picks.
+func (c *Cache[K, V]) evict() {
+	order := c.recency
+	if order == nil {
+		order = c.freqs[c.minFreq]
+	}
+	var victim K
+	for k := range order.Keys() {
+		victim = k
+		break
+	}
+	e := c.entries[victim]
+	c.remove(victim, e)
+	c.stats.Evictions++
+	if c.opts.OnEvict != nil {
+		c.opts.OnEvict(victim, e.value)
+	}
+}
+
+// SyncCache is a Cache that is safe to use from several goroutines. Even Get
+// changes the cache, so every method takes the one lock. OnEvict runs with the
+// lock held and must not call back into the cache.
+type SyncCache[K comparable, V any] struct {
+	mu sync.Mutex
+	c  *Cache[K, V]
+}
+
+// NewSyncCache is NewCache for a SyncCache.
+func NewSyncCache[K comparable, V any](opts CacheOptions[K, V]) *SyncCache[K, V] {
+	return &SyncCache[K, V]{c: NewCache(opts)}
+}
+
+func (s *SyncCache[K, V]) Get(key K) (V, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c.Get(key)
+}
+
+func (s *SyncCache[K, V]) Set(key K, value V) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.c.Set(key, value)
+}
+
+func (s *SyncCache[K, V]) Delete(key K) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c.Delete(key)
+}
+
+func (s *SyncCache[K, V]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c.Len()
+}
+
+func (s *SyncCache[K, V]) Stats() CacheStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c.Stats()
+}
+
+// GetOrLoad returns the cached value for key, or calls load and caches its
+// result. The lock is not held while load runs, so two goroutines that miss
+// on the same key at once may both load it.
+func (s *SyncCache[K, V]) GetOrLoad(key K, load func(K) (V, error)) (V, error) {
+	if v, ok := s.Get(key); ok {
+		return v, nil
+	}
+	v, err := load(key)
+	if err != nil {
+		return v, err
+	}
+	s.Set(key, v)
+	return v, nil
+}
+
+func cacheMain() {
+	var evicted []string
+	onEvict := func(k string, _ int) { evicted = append(evicted, k) }
+
+	lru := NewCache(CacheOptions[string, int]{Policy: LRU, MaxEntries: 2, OnEvict: onEvict})
+	lru.Set("I", 1)
+	lru.Set("Love", 2)
+	lru.Get("I") // "Love" is now the least recently used
+	lru.Set("You", 3)
+	lru.Set("Go", 4)
+	_, ok := lru.Get("Love")
+	fmt.Println(evicted, ok, lru.Stats()) // [Love I] false {1 1 2}
+
+	evicted = nil
+	lfu := NewCache(CacheOptions[string, int]{Policy: LFU, MaxEntries: 2, OnEvict: onEvict})
+	lfu.Set("I", 1)
+	lfu.Set("Love", 2)
+	lfu.Get("I")
+	lfu.Get("Love")
+	lfu.Get("Love") // I used twice, Love three times
+	lfu.Set("You", 3)
+	lfu.Set("Go", 4)     // You and Go are both used once: You is older
+	fmt.Println(evicted) // [I You]
+
+	// Limit the total size of the values instead of their number.
+	evicted = nil
+	bySize := NewCache(CacheOptions[string, string]{
+		MaxWeight: 10,
+		Weight:    func(k, v string) int64 { return int64(len(v)) },
+		OnEvict:   func(k, _ string) { evicted = append(evicted, k) },
+	})
+	bySize.Set("a", "12345")
+	bySize.Set("b", "1234")
+	bySize.Set("c", "123")
+	fmt.Println(evicted, bySize.Len(), bySize.Weight()) // [a] 2 7
+
+	var wg sync.WaitGroup
+	shared := NewSyncCache(CacheOptions[int, int]{MaxEntries: 10})
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			shared.GetOrLoad(i%20, func(k int) (int, error) { return k * k, nil })
+		}()
+	}
+	wg.Wait()
+	fmt.Println(shared.Len()) // 10
+}
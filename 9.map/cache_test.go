@@ -0,0 +1,160 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"testing"
+)
+
+// recorder collects the keys a cache evicts.
+type recorder struct{ keys []string }
+
+func (r *recorder) onEvict(k string, _ int) { r.keys = append(r.keys, k) }
+
+func TestCacheLRU(t *testing.T) {
+	var r recorder
+	c := NewCache(CacheOptions[string, int]{Policy: LRU, MaxEntries: 3, OnEvict: r.onEvict})
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	c.Get("a")     // order: b c a
+	c.Set("b", 20) // replacing counts as a use: c a b
+	c.Peek("c")    // Peek is not a use
+	c.Set("d", 4)  // evicts c
+	c.Set("e", 5)  // evicts a
+	if want := []string{"c", "a"}; !slices.Equal(r.keys, want) {
+		t.Errorf("evicted %v, want %v", r.keys, want)
+	}
+	if v, ok := c.Get("b"); !ok || v != 20 {
+		t.Errorf(`Get("b") = %d, %v; want 20, true`, v, ok)
+	}
+	if c.Len() != 3 {
+		t.Errorf("Len = %d, want 3", c.Len())
+	}
+}
+
+func TestCacheLFU(t *testing.T) {
+	var r recorder
+	c := NewCache(CacheOptions[string, int]{Policy: LFU, MaxEntries: 3, OnEvict: r.onEvict})
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	c.Get("a")
+	c.Get("a") // a: 3 uses, b: 1, c: 1
+	c.Get("c") // c: 2
+	c.Set("d", 4)
+	// b has the fewest uses.
+	c.Set("e", 5)
+	// d and e both have one use: d is the least recently used of them.
+	if want := []string{"b", "d"}; !slices.Equal(r.keys, want) {
+		t.Errorf("evicted %v, want %v", r.keys, want)
+	}
+
+	// Deleting the only entry with one use leaves c as the least used.
+	c.Delete("e") // c: 2, a: 3
+	c.Set("f", 6) // f: 1
+	c.Get("f")
+	c.Get("f") // f: 3
+	c.Set("g", 7)
+	if r.keys[len(r.keys)-1] != "c" {
+		t.Errorf("evicted %v, want c last", r.keys)
+	}
+}
+
+func TestCacheWeight(t *testing.T) {
+	var r recorder
+	c := NewCache(CacheOptions[string, int]{
+		MaxWeight: 10,
+		Weight:    func(_ string, v int) int64 { return int64(v) },
+		OnEvict:   r.onEvict,
+	})
+	c.Set("a", 4)
+	c.Set("b", 4)
+	c.Set("c", 4) // 12 > 10: a goes
+	if c.Weight() != 8 || c.Len() != 2 {
+		t.Errorf("Weight %d, Len %d; want 8, 2", c.Weight(), c.Len())
+	}
+	c.Set("b", 9) // b grows to 9, so c goes, not b
+	if c.Weight() != 9 || c.Len() != 1 {
+		t.Errorf("after growing b: Weight %d, Len %d; want 9, 1", c.Weight(), c.Len())
+	}
+
+	// An entry heavier than MaxWeight empties the cache and is then evicted
+	// itself.
+	c.Set("huge", 11)
+	if c.Len() != 0 || c.Weight() != 0 {
+		t.Errorf("after huge entry: Len %d, Weight %d; want 0, 0", c.Len(), c.Weight())
+	}
+	if want := []string{"a", "c", "b", "huge"}; !slices.Equal(r.keys, want) {
+		t.Errorf("evicted %v, want %v", r.keys, want)
+	}
+	if _, ok := c.Peek("huge"); ok {
+		t.Error("huge entry is still cached")
+	}
+}
+
+func TestCacheOnEvictNotForDelete(t *testing.T) {
+	var r recorder
+	c := NewCache(CacheOptions[string, int]{MaxEntries: 2, OnEvict: r.onEvict})
+	c.Set("a", 1)
+	c.Set("a", 2) // replaced, not evicted
+	if !c.Delete("a") || c.Delete("a") {
+		t.Error("Delete reported the wrong presence")
+	}
+	if len(r.keys) != 0 {
+		t.Errorf("OnEvict called for %v, want no calls", r.keys)
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	c := NewCache(CacheOptions[string, int]{MaxEntries: 1})
+	if got := c.Stats().HitRate(); got != 0 {
+		t.Errorf("HitRate with no lookups = %v, want 0", got)
+	}
+	c.Set("a", 1)
+	c.Get("a")
+	c.Get("a")
+	c.Get("b")
+	c.Peek("a") // not counted
+	c.Set("b", 2)
+	c.Set("c", 3)
+	want := CacheStats{Hits: 2, Misses: 1, Evictions: 2}
+	if got := c.Stats(); got != want {
+		t.Errorf("Stats = %+v, want %+v", got, want)
+	}
+	if got := c.Stats().HitRate(); got != 2.0/3 {
+		t.Errorf("HitRate = %v, want 2/3", got)
+	}
+}
+
+func TestNewCacheNeedsALimit(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewCache without limits did not panic")
+		}
+	}()
+	NewCache(CacheOptions[string, int]{})
+}
+
+func TestSyncCache(t *testing.T) {
+	c := NewSyncCache(CacheOptions[int, int]{Policy: LFU, MaxEntries: 10})
+	var wg sync.WaitGroup
+	for i := range 200 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.GetOrLoad(i%30, func(k int) (int, error) { return k * k, nil })
+			if err != nil || v != (i%30)*(i%30) {
+				t.Errorf("GetOrLoad(%d) = %d, %v", i%30, v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if c.Len() != 10 {
+		t.Errorf("Len = %d, want 10", c.Len())
+	}
+	s := c.Stats()
+	if s.Hits+s.Misses != 200 {
+		t.Errorf("Stats = %+v, want 200 lookups", s)
+	}
+}
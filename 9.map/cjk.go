@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Chinese and Japanese are written without spaces between words, so for
+// wordCount "我爱你。" is a single word. Without a dictionary the usual way to
+// count such text is by characters (unigrams) or by overlapping pairs of
+// characters (bigrams): "我爱你" → 我爱, 爱你. With a word list, forward maximum
+// matching takes the longest dictionary word at each position instead.
+// Korean uses spaces but is split the same way when it is not.
+//
+// Only an Analyzer segments text, through its CJK field. wordCount is the
+// Tour's exercise and splits on white space alone, so it still counts a whole
+// sentence of Chinese as one word.
+
+// isCJK reports whether r is written without spaces between words: Han,
+// Hiragana, Katakana or Hangul, plus the Japanese prolonged sound mark.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) || r == 'ー'
+}
+
+// A Segmenter splits a run of CJK characters into words.
+type Segmenter interface {
+	Segment(run []rune) []string
+}
+
+// SegmenterFunc lets an ordinary function be a Segmenter.
+type SegmenterFunc func(run []rune) []string
+
+func (f SegmenterFunc) Segment(run []rune) []string { return f(run) }
+
+var (
+	// Unigrams splits a run into single characters.
+	Unigrams = SegmenterFunc(unigrams)
+	// Bigrams splits a run into overlapping pairs of characters.
+	// A run of one character is kept as it is.
+	Bigrams = SegmenterFunc(bigrams)
+	// UniBigrams returns both the unigrams and the bigrams of a run.
+	UniBigrams = SegmenterFunc(func(run []rune) []string {
+		if len(run) == 1 {
+			return unigrams(run)
+		}
+		return append(unigrams(run), bigrams(run)...)
+	})
+)
+
+func unigrams(run []rune) []string {
+	words := make([]string, len(run))
+	for i, r := range run {
+		words[i] = string(r)
+	}
+	return words
+}
+
+func bigrams(run []rune) []string {
+	if len(run) == 1 {
+		return []string{string(run)}
+	}
+	words := make([]string, 0, len(run)-1)
+	for i := 0; i+1 < len(run); i++ {
+		words = append(words, string(run[i:i+2]))
+	}
+	return words
+}
+
+// Dictionary is a Segmenter that uses forward maximum matching: at each
+// position it takes the longest word of the dictionary that starts there, or a
+// single character when none does.
+type Dictionary struct {
+	words  map[string]bool
+	maxLen int // longest word, in runes
+}
+
+// NewDictionary returns a dictionary of the given words.
+func NewDictionary(words ...string) *Dictionary {
+	d := &Dictionary{words: make(map[string]bool, len(words))}
+	for _, w := range words {
+		d.Add(w)
+	}
+	return d
+}
+
+// LoadDictionary reads a word list with one word per line. Anything after the
+// first space or tab, such as a frequency, is ignored, as are blank lines and
+// lines starting with #.
+func LoadDictionary(r io.Reader) (*Dictionary, error) {
+	d := NewDictionary()
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			line = line[:i]
+		}
+		d.Add(line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Add adds a word to the dictionary.
+func (d *Dictionary) Add(word string) {
+	d.words[word] = true
+	d.maxLen = max(d.maxLen, utf8.RuneCountInString(word))
+}
+
+// Len returns the number of words in the dictionary.
+func (d *Dictionary) Len() int { return len(d.words) }
+
+func (d *Dictionary) Segment(run []rune) []string {
+	var words []string
+	for i := 0; i < len(run); {
+		n := min(d.maxLen, len(run)-i)
+		for ; n > 1; n-- {
+			if d.words[string(run[i:i+n])] {
+				break
+			}
+		}
+		n = max(n, 1)
+		words = append(words, string(run[i:i+n]))
+		i += n
+	}
+	return words
+}
+
+// segment splits a word from ScanTokens into its CJK and non-CJK parts, and
+// the CJK parts further with seg: "Go语言" → Go, 语言 with a dictionary.
+func segment(word string, seg Segmenter) []string {
+	var words []string
+	var run []rune
+	start := 0
+	for i, r := range word {
+		if isCJK(r) {
+			if i > start && len(run) == 0 {
+				words = append(words, word[start:i])
+			}
+			run = append(run, r)
+			continue
+		}
+		if len(run) > 0 {
+			words = append(words, seg.Segment(run)...)
+			run = run[:0]
+			start = i
+		}
+	}
+	if len(run) > 0 {
+		words = append(words, seg.Segment(run)...)
+	} else if start < len(word) {
+		words = append(words, word[start:])
+	}
+	return words
+}
+
+// parseSegmenter returns the Segmenter named by the -cjk flag: unigram,
+// bigram, both, or dict=FILE for a dictionary.
+func parseSegmenter(name string) (Segmenter, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case "unigram":
+		return Unigrams, nil
+	case "bigram":
+		return Bigrams, nil
+	case "both":
+		return UniBigrams, nil
+	}
+	file, ok := strings.CutPrefix(name, "dict=")
+	if !ok {
+		return nil, fmt.Errorf("unknown -cjk %q, want unigram, bigram, both or dict=FILE", name)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadDictionary(f)
+}
+
+func cjkMain() {
+	text := "我爱你。我爱Go语言！"
+	// wordCount does not segment: the sentence is one word.
+	fmt.Println(wordCount(text), len(wordCount(text))) // map[我爱你。我爱Go语言！:1] 1
+
+	a := &Analyzer{Fold: true, CJK: Bigrams}
+	rep, _ := a.Analyze(strings.NewReader(text))
+	fmt.Println(rep.Words) // map[go:1 我爱:2 爱你:1 语言:1]
+
+	dict, _ := LoadDictionary(strings.NewReader("# word list\n爱 100\n语言\n我们\n编程语言\n"))
+	a.CJK = dict
+	rep, _ = a.Analyze(strings.NewReader(text + " 我们学习编程语言。"))
+	fmt.Println(Top(rep.Words, 4))       // [{我 2} {爱 2} {go 1} {习 1}]
+	fmt.Println(segment("学习编程语言", dict)) // [学 习 编程语言]
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSegment(t *testing.T) {
+	dict := NewDictionary("语言", "编程语言", "我们")
+	tests := []struct {
+		word string
+		seg  Segmenter
+		want []string
+	}{
+		{"hello", Bigrams, []string{"hello"}},
+		{"我爱你", Unigrams, []string{"我", "爱", "你"}},
+		{"我爱你", Bigrams, []string{"我爱", "爱你"}},
+		{"我爱你", UniBigrams, []string{"我", "爱", "你", "我爱", "爱你"}},
+		{"我", Bigrams, []string{"我"}},
+		{"我", UniBigrams, []string{"我"}},
+		// CJK runs are cut out of the words around them.
+		{"Go语言", dict, []string{"Go", "语言"}},
+		{"语言Go", dict, []string{"语言", "Go"}},
+		{"Go语言2学习x", Unigrams, []string{"Go", "语", "言", "2", "学", "习", "x"}},
+		{"カタカナーです", Bigrams, []string{"カタ", "タカ", "カナ", "ナー", "ーで", "です"}},
+		{"한국어를", Unigrams, []string{"한", "국", "어", "를"}},
+	}
+	for _, tt := range tests {
+		if got := segment(tt.word, tt.seg); !slices.Equal(got, tt.want) {
+			t.Errorf("segment(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestDictionarySegment(t *testing.T) {
+	dict := NewDictionary("编程", "编程语言", "语言", "学习")
+	tests := []struct {
+		run  string
+		want []string
+	}{
+		// The longest match wins over a shorter one at the same position.
+		{"学习编程语言", []string{"学习", "编程语言"}},
+		{"编程语", []string{"编程", "语"}},
+		// Characters not starting any word stand alone.
+		{"我学习", []string{"我", "学习"}},
+		{"我", []string{"我"}},
+	}
+	for _, tt := range tests {
+		if got := dict.Segment([]rune(tt.run)); !slices.Equal(got, tt.want) {
+			t.Errorf("Segment(%q) = %q, want %q", tt.run, got, tt.want)
+		}
+	}
+	if got := NewDictionary().Segment([]rune("我爱你")); !slices.Equal(got, []string{"我", "爱", "你"}) {
+		t.Errorf("empty dictionary: %q", got)
+	}
+}
+
+func TestLoadDictionary(t *testing.T) {
+	d, err := LoadDictionary(strings.NewReader("# comment\n\n  爱 100\n语言\t5\n编程语言\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Len() != 3 || d.maxLen != 4 {
+		t.Errorf("Len, maxLen = %d, %d, want 3, 4", d.Len(), d.maxLen)
+	}
+	if got := d.Segment([]rune("爱编程语言")); !slices.Equal(got, []string{"爱", "编程语言"}) {
+		t.Errorf("Segment = %q", got)
+	}
+}
+
+func TestParseSegmenter(t *testing.T) {
+	for _, name := range []string{"unigram", "bigram", "both"} {
+		if seg, err := parseSegmenter(name); seg == nil || err != nil {
+			t.Errorf("parseSegmenter(%q) = %v, %v", name, seg, err)
+		}
+	}
+	if seg, err := parseSegmenter(""); seg != nil || err != nil {
+		t.Errorf(`parseSegmenter("") = %v, %v, want nil, nil`, seg, err)
+	}
+	if _, err := parseSegmenter("trigram"); err == nil {
+		t.Error("parseSegmenter accepted trigram")
+	}
+	if _, err := parseSegmenter("dict=" + t.TempDir() + "/missing.txt"); err == nil {
+		t.Error("parseSegmenter accepted a missing dictionary")
+	}
+}
+
+func TestAnalyzeCJK(t *testing.T) {
+	a := &Analyzer{Fold: true, CJK: Bigrams, NGrams: []int{2}}
+	rep, err := a.Analyze(strings.NewReader("我爱你。我爱Go语言！"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (map[string]int{"我爱": 2, "爱你": 1, "go": 1, "语言": 1}); !maps.Equal(rep.Words, want) {
+		t.Errorf("Words = %v, want %v", rep.Words, want)
+	}
+	// N-grams run over the segmented words.
+	if rep.NGrams[2]["我爱 go"] != 1 {
+		t.Errorf("2-grams = %v", rep.NGrams[2])
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"hash/maphash"
+	"iter"
+	"math/bits"
+)
+
+// A built-in map needs a comparable key type: a []byte or a struct holding a
+// slice cannot be a key. HashMap takes the hash and equality functions from
+// the caller instead, so any type can be a key.
+//
+// It uses open addressing: all entries live in one slice, and a key that
+// collides with another is stored in the next free slot. With Robin Hood
+// hashing an entry that is far from its home slot takes the place of one that
+// is closer to home, which keeps every probe sequence short even when the
+// table is nearly full, and lets a lookup stop as soon as it meets an entry
+// closer to home than the key would be.
+
+// HashMapOptions configures a HashMap.
+type HashMapOptions struct {
+	// MaxLoad is the fraction of slots that may be full before the table
+	// doubles, between 0.1 and 0.95. Zero means 0.875.
+	MaxLoad float64
+	// Capacity is the number of entries to make room for up front.
+	Capacity int
+}
+
+// HashMap is an open-addressing hash table with Robin Hood probing.
+// Unlike a built-in map it cannot hash its keys by itself, so its zero value
+// is not usable: create one with NewHashMap.
+type HashMap[K, V any] struct {
+	hash    func(K) uint64
+	equal   func(a, b K) bool
+	slots   []hashSlot[K, V]
+	n       int
+	maxLoad float64
+	grow    int // n at which the table doubles
+}
+
+type hashSlot[K, V any] struct {
+	hash  uint64
+	dist  uint32 // 1 + distance from the home slot; 0 for an empty slot
+	key   K
+	value V
+}
+
+// NewHashMap returns an empty map that uses hash and equal on its keys.
+// Keys that are equal must have the same hash.
+func NewHashMap[K, V any](hash func(K) uint64, equal func(a, b K) bool, opts HashMapOptions) *HashMap[K, V] {
+	if opts.MaxLoad == 0 {
+		opts.MaxLoad = 0.875
+	}
+	if opts.MaxLoad < 0.1 || opts.MaxLoad > 0.95 {
+		panic(fmt.Sprintf("NewHashMap: MaxLoad %v out of range [0.1, 0.95]", opts.MaxLoad))
+	}
+	m := &HashMap[K, V]{hash: hash, equal: equal, maxLoad: opts.MaxLoad}
+	m.resize(max(8, int(float64(opts.Capacity)/opts.MaxLoad)+1))
+	return m
+}
+
+// resize moves the entries to a table of at least size slots, rounded up to
+// a power of two so that hash & mask picks the home slot.
+func (m *HashMap[K, V]) resize(size int) {
+	size = 1 << bits.Len(uint(size-1))
+	old := m.slots
+	m.slots = make([]hashSlot[K, V], size)
+	m.grow = int(float64(size) * m.maxLoad)
+	for i := range old {
+		if old[i].dist != 0 {
+			m.place(old[i])
+		}
+	}
+}
+
+// place stores s, which holds a key that is not in the table yet.
+func (m *HashMap[K, V]) place(s hashSlot[K, V]) {
+	mask := uint64(len(m.slots) - 1)
+	i := s.hash & mask
+	s.dist = 1
+	for {
+		cur := &m.slots[i]
+		if cur.dist == 0 {
+			*cur = s
+			return
+		}
+		if cur.dist < s.dist {
+			// Take from the rich: cur is closer to home than s, so s gets the
+			// slot and cur moves on.
+			*cur, s = s, *cur
+		}
+		i = (i + 1) & mask
+		s.dist++
+	}
+}
+
+// find returns the slot index of key, or -1.
+func (m *HashMap[K, V]) find(key K, h uint64) int {
+	mask := uint64(len(m.slots) - 1)
+	i := h & mask
+	for dist := uint32(1); ; dist++ {
+		s := &m.slots[i]
+		if s.dist < dist {
+			// Empty, or an entry closer to home than key would be: had key
+			// been inserted, it would have taken this slot.
+			return -1
+		}
+		if s.hash == h && m.equal(s.key, key) {
+			return int(i)
+		}
+		i = (i + 1) & mask
+	}
+}
+
+// Len returns the number of entries.
+func (m *HashMap[K, V]) Len() int { return m.n }
+
+// Get returns the value for key and whether it is present.
+func (m *HashMap[K, V]) Get(key K) (V, bool) {
+	if i := m.find(key, m.hash(key)); i >= 0 {
+		return m.slots[i].value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Set sets the value for key.
+func (m *HashMap[K, V]) Set(key K, value V) {
+	m.Update(key, func(V, bool) V { return value })
+}
+
+// Update sets the value for key to f(old, present), hashing key only once.
+// It is how a count is incremented: m.Update(w, func(n int, _ bool) int { return n + 1 }).
+func (m *HashMap[K, V]) Update(key K, f func(old V, present bool) V) {
+	h := m.hash(key)
+	if i := m.find(key, h); i >= 0 {
+		m.slots[i].value = f(m.slots[i].value, true)
+		return
+	}
+	var zero V
+	v := f(zero, false)
+	if m.n >= m.grow {
+		m.resize(2 * len(m.slots))
+	}
+	m.place(hashSlot[K, V]{hash: h, key: key, value: v})
+	m.n++
+}
+
+// Delete removes key and reports whether it was present.
+func (m *HashMap[K, V]) Delete(key K) bool {
+	i := m.find(key, m.hash(key))
+	if i < 0 {
+		return false
+	}
+	// Shift the following entries of the run back by one slot, so no
+	// tombstone is needed and lookups still stop at the right place.
+	mask := len(m.slots) - 1
+	for {
+		next := (i + 1) & mask
+		if m.slots[next].dist <= 1 {
+			break
+		}
+		m.slots[i] = m.slots[next]
+		m.slots[i].dist--
+		i = next
+	}
+	m.slots[i] = hashSlot[K, V]{}
+	m.n--
+	return true
+}
+
+// All yields the entries in table order, which depends on the hashes.
+func (m *HashMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for i := range m.slots {
+			if s := &m.slots[i]; s.dist != 0 && !yield(s.key, s.value) {
+				return
+			}
+		}
+	}
+}
+
+// HashMapStats describes how full a HashMap is and how far entries are from
+// their home slots.
+type HashMapStats struct {
+	Len, Slots int
+	Load       float64
+	MeanProbe  float64 // average number of slots a lookup of a present key visits
+	MaxProbe   int
+}
+
+// Stats returns the current statistics of m.
+func (m *HashMap[K, V]) Stats() HashMapStats {
+	st := HashMapStats{Len: m.n, Slots: len(m.slots), Load: float64(m.n) / float64(len(m.slots))}
+	total := 0
+	for i := range m.slots {
+		d := int(m.slots[i].dist)
+		total += d
+		st.MaxProbe = max(st.MaxProbe, d)
+	}
+	if m.n > 0 {
+		st.MeanProbe = float64(total) / float64(m.n)
+	}
+	return st
+}
+
+// hashSeed is shared by the hash functions below, so equal keys hash equally
+// within one run of the program. It differs from run to run, like the built-in
+// map's, so an attacker cannot choose keys that collide.
+var hashSeed = maphash.MakeSeed()
+
+// StringHash hashes a string.
+func StringHash(s string) uint64 { return maphash.String(hashSeed, s) }
+
+// BytesHash hashes a byte slice; use it with bytes.Equal.
+func BytesHash(b []byte) uint64 { return maphash.Bytes(hashSeed, b) }
+
+// ComparableHash hashes any comparable value the way a built-in map would.
+func ComparableHash[K comparable](k K) uint64 { return maphash.Comparable(hashSeed, k) }
+
+// Equal is the == operator as a function, for comparable keys.
+func Equal[K comparable](a, b K) bool { return a == b }
+
+func hashMapMain() {
+	// Count words with a HashMap.
+	counts := NewHashMap[string, int](StringHash, Equal, HashMapOptions{})
+	for _, w := range []string{"I", "Love", "You!", "I", "Love", "You!", "I", "Love", "You!"} {
+		counts.Update(w, func(n int, _ bool) int { return n + 1 })
+	}
+	n, ok := counts.Get("Love")
+	fmt.Println(counts.Len(), n, ok) // 3 3 true
+
+	// []byte keys, which a built-in map cannot have.
+	lines := NewHashMap[[]byte, int](BytesHash, bytes.Equal, HashMapOptions{Capacity: 100})
+	lines.Set([]byte("GET /"), 200)
+	lines.Set([]byte("GET /missing"), 404)
+	code, _ := lines.Get([]byte("GET /missing"))
+	fmt.Println(code, lines.Stats().Slots) // 404 128
+
+	// Filling the table to the maximum load keeps probes short.
+	m := NewHashMap[int, int](ComparableHash[int], Equal, HashMapOptions{MaxLoad: 0.95})
+	for i := range 10000 {
+		m.Set(i, i*i)
+	}
+	for i := range 5000 {
+		m.Delete(2 * i)
+	}
+	v, _ := m.Get(99)
+	_, ok = m.Get(98)
+	st := m.Stats()
+	fmt.Println(st.Len, v, ok, st.MeanProbe < 3) // 5000 9801 false true
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+// layout returns the keys and probe distances of m's slots in table order,
+// with -1 for an empty slot.
+func layout(m *HashMap[int, int]) (keys []int, dists []uint32) {
+	for _, s := range m.slots {
+		if s.dist == 0 {
+			keys = append(keys, -1)
+		} else {
+			keys = append(keys, s.key)
+		}
+		dists = append(dists, s.dist)
+	}
+	return keys, dists
+}
+
+// checkHashMap checks m against want and the invariants of the table: every
+// entry's dist matches its distance from home, and a run never starts with an
+// entry away from home, which is what backward-shift deletion preserves.
+func checkHashMap(t *testing.T, m *HashMap[int, int], want map[int]int) {
+	t.Helper()
+	if m.Len() != len(want) {
+		t.Fatalf("Len = %d, want %d", m.Len(), len(want))
+	}
+	for k, v := range want {
+		if got, ok := m.Get(k); !ok || got != v {
+			t.Fatalf("Get(%d) = %d, %v, want %d, true", k, got, ok, v)
+		}
+	}
+	n := 0
+	for k, v := range m.All() {
+		if want[k] != v {
+			t.Fatalf("All yields %d: %d, want %d", k, v, want[k])
+		}
+		n++
+	}
+	if n != len(want) {
+		t.Fatalf("All yields %d entries, want %d", n, len(want))
+	}
+	mask := uint64(len(m.slots) - 1)
+	for i, s := range m.slots {
+		if s.dist == 0 {
+			continue
+		}
+		if home := s.hash & mask; uint64(s.dist) != (uint64(i)-home)&mask+1 {
+			t.Fatalf("slot %d: dist %d, but home is %d", i, s.dist, home)
+		}
+		if prev := m.slots[(uint64(i)-1)&mask]; s.dist > prev.dist+1 {
+			t.Fatalf("slot %d: dist %d after a slot with dist %d", i, s.dist, prev.dist)
+		}
+	}
+}
+
+func TestHashMapRobinHood(t *testing.T) {
+	// Keys 1, 2 and 3 share home slot 0, 101 has home slot 1.
+	m := NewHashMap[int, int](func(k int) uint64 { return uint64(k / 100) }, Equal, HashMapOptions{})
+	for _, k := range []int{1, 2, 101, 3} {
+		m.Set(k, k)
+	}
+	// 3 is further from home than 101 at slot 2, so it takes that slot.
+	keys, dists := layout(m)
+	if want := []int{1, 2, 3, 101, -1, -1, -1, -1}; !slices.Equal(keys, want) {
+		t.Errorf("keys after inserts = %v, want %v", keys, want)
+	}
+	if want := []uint32{1, 2, 3, 3, 0, 0, 0, 0}; !slices.Equal(dists, want) {
+		t.Errorf("dists after inserts = %v, want %v", dists, want)
+	}
+
+	// Deleting 1 shifts the rest of the run back one slot.
+	if !m.Delete(1) {
+		t.Fatal("Delete(1) = false")
+	}
+	keys, dists = layout(m)
+	if want := []int{2, 3, 101, -1, -1, -1, -1, -1}; !slices.Equal(keys, want) {
+		t.Errorf("keys after delete = %v, want %v", keys, want)
+	}
+	if want := []uint32{1, 2, 2, 0, 0, 0, 0, 0}; !slices.Equal(dists, want) {
+		t.Errorf("dists after delete = %v, want %v", dists, want)
+	}
+	checkHashMap(t, m, map[int]int{2: 2, 3: 3, 101: 101})
+
+	if m.Delete(1) {
+		t.Error("Delete of a missing key = true")
+	}
+	if _, ok := m.Get(4); ok {
+		t.Error("Get of a missing key in a full run succeeded")
+	}
+}
+
+func TestHashMapMatchesMap(t *testing.T) {
+	hashes := []struct {
+		name string
+		hash func(int) uint64
+	}{
+		{"maphash", ComparableHash[int]},
+		{"identity", func(k int) uint64 { return uint64(k) }},
+		// Only 8 distinct hashes: long runs, and many entries that are
+		// displaced and shifted back.
+		{"collisions", func(k int) uint64 { return uint64(k % 8) }},
+	}
+	for _, h := range hashes {
+		t.Run(h.name, func(t *testing.T) {
+			r := rand.New(rand.NewPCG(1, 2))
+			m := NewHashMap[int, int](h.hash, Equal, HashMapOptions{MaxLoad: 0.95})
+			want := make(map[int]int)
+			for i := range 5000 {
+				k := r.IntN(500)
+				switch r.IntN(3) {
+				case 0, 1:
+					m.Set(k, i)
+					want[k] = i
+				case 2:
+					_, present := want[k]
+					if got := m.Delete(k); got != present {
+						t.Fatalf("Delete(%d) = %v, want %v", k, got, present)
+					}
+					delete(want, k)
+				}
+				if i%250 == 0 {
+					checkHashMap(t, m, want)
+				}
+			}
+			checkHashMap(t, m, want)
+		})
+	}
+}
+
+func TestHashMapGrowth(t *testing.T) {
+	m := NewHashMap[int, int](ComparableHash[int], Equal, HashMapOptions{})
+	want := make(map[int]int)
+	slots := m.Stats().Slots
+	for i := range 1000 {
+		m.Update(i, func(old int, present bool) int {
+			if present {
+				t.Fatalf("Update(%d) saw a value before it was set", i)
+			}
+			return i * 2
+		})
+		want[i] = i * 2
+		st := m.Stats()
+		if st.Load > 0.875 {
+			t.Fatalf("after %d entries: load %v above 0.875", i+1, st.Load)
+		}
+		if st.Slots != slots {
+			if st.Slots != 2*slots {
+				t.Fatalf("table grew from %d to %d slots, want doubling", slots, st.Slots)
+			}
+			checkHashMap(t, m, want)
+			slots = st.Slots
+		}
+	}
+	checkHashMap(t, m, want)
+	if slots != 2048 {
+		t.Errorf("1000 entries in %d slots, want 2048", slots)
+	}
+
+	// Capacity makes room up front.
+	m = NewHashMap[int, int](ComparableHash[int], Equal, HashMapOptions{Capacity: 1000})
+	for i := range 1000 {
+		m.Set(i, i)
+	}
+	if st := m.Stats(); st.Slots != 2048 {
+		t.Errorf("with Capacity 1000: %d slots after 1000 entries, want 2048", st.Slots)
+	}
+}
+
+func TestHashMapMaxLoad(t *testing.T) {
+	for _, load := range []float64{0.05, 0.96, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("MaxLoad %v did not panic", load)
+				}
+			}()
+			NewHashMap[int, int](ComparableHash[int], Equal, HashMapOptions{MaxLoad: load})
+		}()
+	}
+}
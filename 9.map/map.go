@@ -2,12 +2,35 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"strings"
 )
 
 var pow = []int{1, 2, 4, 8}
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "analyze" {
+		if err := analyzeCommand(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "analyze:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "kv" {
+		if err := kvCommand(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "kv:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "count" {
+		if err := countCommand(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "count:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// The range form of the for loop iterates over a slice or map.
 	// When ranging over a slice, two values are returned for each iteration. The
 	// first is the index, and the second is a copy of the element at that index.
@@ -52,6 +75,59 @@ func main() {
 	}
 	fmt.Println(m2) // map[0:你 2:爱 5:我]
 
+	// wordCount counts "You!" and "you" apart; analyzeMain shows an Analyzer
+	// that does not. Run "go run 9.map/*.go analyze -h" to analyse files.
+	analyzeMain()
+	// map[i:3 love:3 you:3]
+	// 9 3 0.3333333333333333
+	// [{i love 3} {love you 3}]
+	// [{disk 2} {don't 1} {full 1}]
+	// n,term,count
+	// 1,disk,2
+
+	// treeMain counts a whole directory tree in parallel. Run
+	// "go run 9.map/*.go count -h" to count your own files.
+	treeMain()
+	// 19 [{i 4} {love 4} {you 3}] true
+	// a.txt[{i 3}] b.txt[{go 1}] logs/1.log[{disk 2}] logs/2.log[{ok 1}]
+	// context canceled
+
+	// m2 holds Chinese characters; cjkMain counts words in Chinese text.
+	cjkMain()
+	// map[我爱你。我爱Go语言！:1] 1
+	// map[go:1 我爱:2 爱你:1 语言:1]
+	// [{我 2} {爱 2} {go 1} {习 1}]
+	// [学 习 编程语言]
+
+	// fmt sorts map keys when printing, but range does not: the order is
+	// random. An OrderedMap keeps the insertion order.
+	orderedMain()
+	// map[You!:2 Love:2 I:2]
+	// I You! Go
+	// {"I":2,"You!":2,"Go":1}
+	// 443=https 22=ssh 8080=http
+
+	// HashMap works with keys a built-in map cannot use. bench_test.go
+	// compares it with map and sync.Map.
+	hashMapMain()
+	// 3 3 true
+	// 404 128
+	// 5000 9801 false true
+
+	// v, ok := m[key] followed by m[key] = v makes a map a cache that never
+	// forgets. A Cache is bounded.
+	cacheMain()
+	// [Love I] false {1 1 2}
+	// [I You]
+	// [a] 2 7
+	// 10
+
+	// A Store keeps a map on disk.
+	storeMain()
+	// I:3 Love:10000
+	// false 2 true
+	// false 2
+	// true true 99 3
 }
 
 func wordCount(s string) map[string]int {
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Ranging over a map visits the keys in a random order that changes from run
+// to run; fmt only looks stable because it sorts the keys before printing.
+// OrderedMap remembers the order in which keys were inserted. The entries form
+// a doubly linked list, and a built-in map from key to list element keeps
+// Get, Set and Delete O(1).
+
+// OrderedMap is a map that iterates in insertion order.
+// The zero value is an empty map ready to use. Like a sync.Mutex, an
+// OrderedMap must not be copied after first use; pass a pointer instead.
+type OrderedMap[K comparable, V any] struct {
+	index map[K]*orderedEntry[K, V]
+	// root is a sentinel: root.next is the first entry and root.prev the
+	// last, so inserting and unlinking need no special cases.
+	root orderedEntry[K, V]
+}
+
+type orderedEntry[K comparable, V any] struct {
+	prev, next *orderedEntry[K, V]
+	key        K
+	value      V
+	// deleted is set by Delete. A deleted entry keeps its prev and next, so
+	// a loop that is holding it can still find its way back into the list.
+	deleted bool
+}
+
+// NewOrderedMap returns an empty map.
+func NewOrderedMap[K comparable, V any]() *OrderedMap[K, V] {
+	return new(OrderedMap[K, V])
+}
+
+func (m *OrderedMap[K, V]) init() {
+	if m.index == nil {
+		m.index = make(map[K]*orderedEntry[K, V])
+		m.root.prev, m.root.next = &m.root, &m.root
+	}
+}
+
+// Len returns the number of entries.
+func (m *OrderedMap[K, V]) Len() int { return len(m.index) }
+
+// Get returns the value for key and whether it is present.
+func (m *OrderedMap[K, V]) Get(key K) (V, bool) {
+	if e, ok := m.index[key]; ok {
+		return e.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Set sets the value for key. A new key goes to the back; an existing key
+// keeps its place.
+func (m *OrderedMap[K, V]) Set(key K, value V) {
+	m.init()
+	if e, ok := m.index[key]; ok {
+		e.value = value
+		return
+	}
+	e := &orderedEntry[K, V]{key: key, value: value}
+	m.index[key] = e
+	m.insert(e, m.root.prev)
+}
+
+// Delete removes key and reports whether it was present.
+func (m *OrderedMap[K, V]) Delete(key K) bool {
+	e, ok := m.index[key]
+	if !ok {
+		return false
+	}
+	delete(m.index, key)
+	m.unlink(e)
+	e.deleted = true
+	return true
+}
+
+// insert links e after at.
+func (m *OrderedMap[K, V]) insert(e, at *orderedEntry[K, V]) {
+	e.prev, e.next = at, at.next
+	at.next.prev = e
+	at.next = e
+}
+
+func (m *OrderedMap[K, V]) unlink(e *orderedEntry[K, V]) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+}
+
+// MoveToFront makes key the first entry and reports whether it is present.
+func (m *OrderedMap[K, V]) MoveToFront(key K) bool {
+	e, ok := m.index[key]
+	if ok {
+		m.unlink(e)
+		m.insert(e, &m.root)
+	}
+	return ok
+}
+
+// MoveToBack makes key the last entry and reports whether it is present.
+func (m *OrderedMap[K, V]) MoveToBack(key K) bool {
+	e, ok := m.index[key]
+	if ok {
+		m.unlink(e)
+		m.insert(e, m.root.prev)
+	}
+	return ok
+}
+
+// All yields the entries from first to last. As with a built-in map, the
+// loop may delete entries: an entry deleted before it is reached is not
+// yielded.
+func (m *OrderedMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if m.index == nil {
+			return
+		}
+		for e := m.root.next; e != &m.root; {
+			next := e.next
+			if !e.deleted && !yield(e.key, e.value) {
+				return
+			}
+			e = next
+		}
+	}
+}
+
+// Backward yields the entries from last to first.
+func (m *OrderedMap[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if m.index == nil {
+			return
+		}
+		for e := m.root.prev; e != &m.root; {
+			prev := e.prev
+			if !e.deleted && !yield(e.key, e.value) {
+				return
+			}
+			e = prev
+		}
+	}
+}
+
+// Keys yields the keys in order.
+func (m *OrderedMap[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for k := range m.All() {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// Values yields the values in order.
+func (m *OrderedMap[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, v := range m.All() {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// String formats the map like fmt prints a map, but in insertion order.
+func (m *OrderedMap[K, V]) String() string {
+	var b strings.Builder
+	b.WriteString("map[")
+	for k, v := range m.All() {
+		if b.Len() > len("map[") {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%v:%v", k, v)
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// MarshalJSON encodes the map as a JSON object with its keys in order. Keys
+// are encoded as encoding/json encodes map keys: strings, integers and
+// encoding.TextMarshaler values are allowed.
+func (m *OrderedMap[K, V]) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for k, v := range m.All() {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		ks, err := encodeKey(k)
+		if err != nil {
+			return nil, err
+		}
+		kb, _ := json.Marshal(ks)
+		buf.Write(kb)
+		buf.WriteByte(':')
+		vb, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(vb)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON adds the members of a JSON object to the map in the order
+// they appear.
+func (m *OrderedMap[K, V]) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil // null leaves the map unchanged, as for a built-in map
+	}
+	if tok != json.Delim('{') {
+		return fmt.Errorf("OrderedMap: cannot unmarshal %v into an object", tok)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, err := decodeKey[K](tok.(string))
+		if err != nil {
+			return err
+		}
+		var v V
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		m.Set(key, v)
+	}
+	_, err = dec.Token() // the closing brace
+	return err
+}
+
+func encodeKey(k any) (string, error) {
+	if tm, ok := k.(encoding.TextMarshaler); ok {
+		b, err := tm.MarshalText()
+		return string(b), err
+	}
+	v := reflect.ValueOf(k)
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("OrderedMap: unsupported key type %T", k)
+}
+
+func decodeKey[K comparable](s string) (K, error) {
+	var k K
+	if tu, ok := any(&k).(encoding.TextUnmarshaler); ok {
+		err := tu.UnmarshalText([]byte(s))
+		return k, err
+	}
+	v := reflect.ValueOf(&k).Elem()
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+		return k, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		v.SetInt(n)
+		return k, err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		v.SetUint(n)
+		return k, err
+	}
+	return k, fmt.Errorf("OrderedMap: unsupported key type %T", k)
+}
+
+func orderedMain() {
+	// Count words keeping the order they first appear in.
+	counts := NewOrderedMap[string, int]()
+	for _, w := range strings.Fields("You! Love I You! Love I") {
+		n, _ := counts.Get(w)
+		counts.Set(w, n+1)
+	}
+	fmt.Println(counts) // map[You!:2 Love:2 I:2]
+
+	counts.MoveToFront("I")
+	counts.Delete("Love")
+	counts.Set("Go", 1)
+	for k := range counts.Keys() {
+		fmt.Print(k, " ") // I You! Go
+	}
+	fmt.Println()
+
+	b, _ := json.Marshal(counts)
+	fmt.Println(string(b)) // {"I":2,"You!":2,"Go":1}
+
+	var ports OrderedMap[int, string]
+	json.Unmarshal([]byte(`{"8080":"http","22":"ssh","443":"https"}`), &ports)
+	for port, name := range ports.Backward() {
+		fmt.Print(port, "=", name, " ") // 443=https 22=ssh 8080=http
+	}
+	fmt.Println()
+}
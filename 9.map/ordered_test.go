@@ -0,0 +1,85 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func orderedOf(keys ...string) *OrderedMap[string, int] {
+	m := NewOrderedMap[string, int]()
+	for i, k := range keys {
+		m.Set(k, i)
+	}
+	return m
+}
+
+func TestOrderedMapDeleteWhileIterating(t *testing.T) {
+	tests := []struct {
+		name     string
+		backward bool
+		del      func(m *OrderedMap[string, int], k string)
+		want     []string // keys yielded
+		left     []string // keys remaining
+	}{
+		{"current", false, func(m *OrderedMap[string, int], k string) { m.Delete(k) },
+			[]string{"a", "b", "c", "d"}, nil},
+		{"next", false, func(m *OrderedMap[string, int], k string) {
+			if k == "a" {
+				m.Delete("b")
+			}
+		}, []string{"a", "c", "d"}, []string{"a", "c", "d"}},
+		{"next two", false, func(m *OrderedMap[string, int], k string) {
+			if k == "a" {
+				m.Delete("b")
+				m.Delete("c")
+			}
+		}, []string{"a", "d"}, []string{"a", "d"}},
+		{"next and current", false, func(m *OrderedMap[string, int], k string) {
+			if k == "b" {
+				m.Delete("c")
+				m.Delete("b")
+			}
+		}, []string{"a", "b", "d"}, []string{"a", "d"}},
+		{"previous, backward", true, func(m *OrderedMap[string, int], k string) {
+			if k == "d" {
+				m.Delete("c")
+			}
+		}, []string{"d", "b", "a"}, []string{"a", "b", "d"}},
+	}
+	for _, tt := range tests {
+		m := orderedOf("a", "b", "c", "d")
+		seq := m.All()
+		if tt.backward {
+			seq = m.Backward()
+		}
+		var got []string
+		for k := range seq {
+			got = append(got, k)
+			tt.del(m, k)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: yielded %v, want %v", tt.name, got, tt.want)
+		}
+		if left := slices.Collect(m.Keys()); !slices.Equal(left, tt.left) || m.Len() != len(tt.left) {
+			t.Errorf("%s: left %v (Len %d), want %v", tt.name, left, m.Len(), tt.left)
+		}
+	}
+}
+
+func TestOrderedMapDeleteAndSetAgain(t *testing.T) {
+	m := orderedOf("a", "b", "c")
+	var got []string
+	for k := range m.All() {
+		got = append(got, k)
+		if k == "a" {
+			m.Delete("b")
+			m.Set("b", 9) // a new entry at the back
+		}
+	}
+	if want := []string{"a", "c", "b"}; !slices.Equal(got, want) {
+		t.Errorf("yielded %v, want %v", got, want)
+	}
+	if v, _ := m.Get("b"); v != 9 {
+		t.Errorf(`Get("b") = %d, want 9`, v)
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openStore(t *testing.T, path string, opts StoreOptions) *Store {
+	t.Helper()
+	s, err := OpenStore(path, opts)
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	return s
+}
+
+// fillStore writes n keys to a new store at path and closes it.
+func fillStore(t *testing.T, path string, n int) {
+	t.Helper()
+	s := openStore(t, path, StoreOptions{})
+	for i := range n {
+		if err := s.Put(fmt.Sprint("k", i), fmt.Append(nil, "v", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func appendBytes(t *testing.T, path string, b []byte) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Write(b); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func fileSize(t *testing.T, path string) int64 {
+	t.Helper()
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fi.Size()
+}
+
+// checkKeys checks that s holds exactly k0..k(n-1) with their values.
+func checkKeys(t *testing.T, s *Store, n int) {
+	t.Helper()
+	if s.Len() != n {
+		t.Errorf("Len = %d, want %d", s.Len(), n)
+	}
+	for i := range n {
+		v, ok, err := s.Get(fmt.Sprint("k", i))
+		if want := fmt.Sprint("v", i); err != nil || !ok || string(v) != want {
+			t.Errorf("Get(k%d) = %q, %v, %v; want %q, true, nil", i, v, ok, err, want)
+		}
+	}
+}
+
+func TestStoreTornRecord(t *testing.T) {
+	rec := encodeRecord(opPut, "torn", []byte("value"))
+	tests := []struct {
+		name string
+		tail []byte
+	}{
+		{"header", rec[:recordHeader-3]},
+		{"body", rec[:len(rec)-2]},
+		{"zeros", make([]byte, 40)},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "db")
+		fillStore(t, path, 5)
+		good := fileSize(t, path)
+		appendBytes(t, path, tt.tail)
+
+		s := openStore(t, path, StoreOptions{})
+		checkKeys(t, s, 5)
+		if size, _ := s.Size(); size != good || fileSize(t, path) != good {
+			t.Errorf("torn %s: log is %d bytes, file %d; want both cut back to %d",
+				tt.name, size, fileSize(t, path), good)
+		}
+		// The next write goes where the torn record was.
+		s.Put("k5", []byte("v5"))
+		s.Close()
+		s = openStore(t, path, StoreOptions{})
+		checkKeys(t, s, 6)
+		s.Close()
+	}
+}
+
+func TestStoreChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+	fillStore(t, path, 5)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A flipped bit in the last record: the torn end of an append.
+	last := append([]byte(nil), data...)
+	last[len(last)-1] ^= 1
+	os.WriteFile(path, last, 0o644)
+	s := openStore(t, path, StoreOptions{})
+	if _, ok, _ := s.Get("k4"); ok || s.Len() != 4 {
+		t.Errorf("after a bad last record: k4 present %v, Len %d; want false, 4", ok, s.Len())
+	}
+	s.Close()
+
+	// A flipped bit in the first record, with good records after it, is
+	// damage: Open must not truncate the file.
+	first := append([]byte(nil), data...)
+	first[recordHeader] ^= 1
+	os.WriteFile(path, first, 0o644)
+	if _, err := OpenStore(path, StoreOptions{}); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("OpenStore with a bad first record: %v, want ErrCorrupt", err)
+	}
+	if fileSize(t, path) != int64(len(data)) {
+		t.Errorf("file cut to %d bytes, want it left at %d", fileSize(t, path), len(data))
+	}
+}
+
+func TestStoreReopenAfterCrash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+	s := openStore(t, path, StoreOptions{Sync: SyncNever})
+	for i := range 10 {
+		s.Put(fmt.Sprint("k", i), fmt.Append(nil, "v", i))
+	}
+	s.Put("k3", []byte("old"))
+	s.Put("k3", []byte("v3"))
+	s.Put("gone", []byte("x"))
+	s.Delete("gone")
+	// The process dies here, without Close, in the middle of a Put.
+	rec := encodeRecord(opPut, "k10", []byte("v10"))
+	appendBytes(t, path, rec[:len(rec)/2])
+
+	again := openStore(t, path, StoreOptions{})
+	defer again.Close()
+	checkKeys(t, again, 10)
+	if _, ok, _ := again.Get("gone"); ok {
+		t.Error("deleted key is back")
+	}
+	s.Close()
+}
+
+func TestStoreCompactReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+	s := openStore(t, path, StoreOptions{})
+	for round := range 20 {
+		for i := range 10 {
+			s.Put(fmt.Sprint("k", i), fmt.Append(nil, "v", round))
+		}
+	}
+	for i := range 10 {
+		s.Put(fmt.Sprint("k", i), fmt.Append(nil, "v", i))
+	}
+	s.Put("gone", []byte("x"))
+	s.Delete("gone")
+	before, garbage := s.Size()
+	if err := s.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	after, garbageAfter := s.Size()
+	if garbageAfter != 0 || after != before-garbage {
+		t.Errorf("after Compact: size %d garbage %d; want %d and 0", after, garbageAfter, before-garbage)
+	}
+	checkKeys(t, s, 10)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if fileSize(t, path) != after {
+		t.Errorf("file is %d bytes, want %d", fileSize(t, path), after)
+	}
+	if _, err := os.Stat(path + ".compact"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	s = openStore(t, path, StoreOptions{})
+	defer s.Close()
+	checkKeys(t, s, 10)
+	if _, ok, _ := s.Get("gone"); ok {
+		t.Error("deleted key is back after compaction")
+	}
+}
+
+func TestStoreCloseDuringAutoCompact(t *testing.T) {
+	for range 20 {
+		path := filepath.Join(t.TempDir(), "db")
+		s := openStore(t, path, StoreOptions{Sync: SyncNever, CompactRatio: 0.5})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; ; i++ {
+				if err := s.Put("k", fmt.Append(nil, i)); err != nil {
+					if !errors.Is(err, ErrClosed) {
+						t.Error(err)
+					}
+					return
+				}
+			}
+		}()
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+		<-done
+	}
+}
+
+func TestStoreLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+	s := openStore(t, path, StoreOptions{})
+	if err := s.Put(strings.Repeat("k", MaxKeyLen+1), nil); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Put with a key over MaxKeyLen: %v, want ErrTooLarge", err)
+	}
+	long := strings.Repeat("k", MaxKeyLen)
+	if err := s.Put(long, []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	s.Put("after", []byte("v"))
+	s.Close()
+
+	s = openStore(t, path, StoreOptions{})
+	if s.Len() != 2 {
+		t.Errorf("Len after reopening = %d, want 2", s.Len())
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put("k", nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("Put after Close: %v, want ErrClosed", err)
+	}
+}
+
+func TestStoreCorruptLength(t *testing.T) {
+	// A header that claims a 1 GiB value, with nothing after it.
+	rec := encodeRecord(opPut, "k", []byte("v"))
+	binary.LittleEndian.PutUint32(rec[9:], MaxValueLen)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, _, _, _, err := readRecord(bytes.NewReader(rec), int64(len(rec)))
+	runtime.ReadMemStats(&after)
+	if err != errBadRecord {
+		t.Errorf("readRecord = %v, want errBadRecord", err)
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 1<<20 {
+		t.Errorf("readRecord allocated %d bytes for a %d-byte file", alloc, len(rec))
+	}
+
+	// Open treats it as a torn append.
+	path := filepath.Join(t.TempDir(), "db")
+	fillStore(t, path, 3)
+	good := fileSize(t, path)
+	appendBytes(t, path, rec)
+	s := openStore(t, path, StoreOptions{})
+	defer s.Close()
+	checkKeys(t, s, 3)
+	if fileSize(t, path) != good {
+		t.Errorf("file is %d bytes, want it cut back to %d", fileSize(t, path), good)
+	}
+}
+
+func TestStoreRange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+	s := openStore(t, path, StoreOptions{})
+	for _, k := range []string{"b", "d", "a", "c"} {
+		s.Put(k, []byte(strings.ToUpper(k)))
+	}
+	s.Put("b", []byte("B2"))
+	s.Delete("d")
+
+	var keys, values []string
+	err := s.Range(func(k string, v []byte) bool {
+		keys = append(keys, k)
+		values = append(values, string(v))
+		// f may write to the store; Range does not see the write.
+		s.Put("z", []byte("Z"))
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(keys, []string{"a", "b", "c"}) || !slices.Equal(values, []string{"A", "B2", "C"}) {
+		t.Errorf("Range = %q %q, want [a b c] [A B2 C]", keys, values)
+	}
+
+	keys = keys[:0]
+	s.Range(func(k string, v []byte) bool {
+		keys = append(keys, k)
+		return k < "b"
+	})
+	if !slices.Equal(keys, []string{"a", "b"}) {
+		t.Errorf("Range stopped after %q, want [a b]", keys)
+	}
+
+	s.Close()
+	if err := s.Range(func(string, []byte) bool { return true }); !errors.Is(err, ErrClosed) {
+		t.Errorf("Range after Close: %v, want ErrClosed", err)
+	}
+}
+
+func TestStoreDeleteMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+	s := openStore(t, path, StoreOptions{})
+	defer s.Close()
+	s.Put("k", []byte("v"))
+	size, _ := s.Size()
+	if err := s.Delete("missing"); err != nil {
+		t.Errorf("Delete of a missing key: %v", err)
+	}
+	if after, _ := s.Size(); after != size {
+		t.Errorf("Delete of a missing key wrote %d bytes", after-size)
+	}
+
+	s.Delete("k")
+	size, _ = s.Size()
+	s.Delete("k")
+	if after, _ := s.Size(); after != size || s.Len() != 0 {
+		t.Errorf("second Delete wrote %d bytes, Len %d", after-size, s.Len())
+	}
+}
+
+func TestStoreSyncInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+	s := openStore(t, path, StoreOptions{Sync: SyncInterval, SyncEvery: 5 * time.Millisecond})
+	dirty := func() bool {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return s.dirty
+	}
+	s.Put("k", []byte("v"))
+	for deadline := time.Now().Add(5 * time.Second); dirty(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("no background sync within 5s")
+		}
+	}
+	// Close stops the background loop, which would otherwise leak.
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Sync(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Sync after Close: %v, want ErrClosed", err)
+	}
+	s = openStore(t, path, StoreOptions{})
+	defer s.Close()
+	if v, ok, _ := s.Get("k"); !ok || string(v) != "v" {
+		t.Errorf("Get(k) after reopening = %q, %v", v, ok)
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+	"text/tabwriter"
+)
+
+// Counting words is a map-reduce: every file can be counted on its own (map),
+// and the partial counts are then added up (reduce). CountTree runs the map
+// step in a fixed number of worker goroutines fed by a channel of file paths;
+// each worker adds its files into its own Report, so the workers never share
+// a map, and the few partial Reports are merged at the end.
+
+// FileReport is the result for one file.
+type FileReport struct {
+	Path   string
+	Tokens int
+	Top    []WordCount
+}
+
+// TreeReport is the result for a directory tree.
+type TreeReport struct {
+	Total *Report
+	Files []FileReport // sorted by path
+}
+
+// CountOptions configures CountTree.
+type CountOptions struct {
+	Workers int // number of files counted at once; 0 means runtime.NumCPU()
+	TopN    int // length of each FileReport.Top
+}
+
+// CountTree counts the words of every regular file under root. It stops at
+// the first error, or when ctx is cancelled, and returns that error.
+func CountTree(ctx context.Context, root string, a *Analyzer, opts CountOptions) (*TreeReport, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	paths := make(chan string)
+	go func() {
+		defer close(paths)
+		err := walkFiles(root, func(path string) error {
+			select {
+			case paths <- path:
+				return nil
+			case <-ctx.Done():
+				return context.Cause(ctx)
+			}
+		})
+		if err != nil {
+			cancel(err)
+		}
+	}()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		partials []*Report
+		files    []FileReport
+	)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			partial := NewReport()
+			var mine []FileReport
+			for path := range paths {
+				rep, err := countFile(ctx, path, a)
+				if err != nil {
+					cancel(err)
+					continue // drain paths so the walker can stop
+				}
+				partial.Merge(rep)
+				mine = append(mine, FileReport{path, rep.Tokens, Top(rep.Words, opts.TopN)})
+			}
+			mu.Lock()
+			partials = append(partials, partial)
+			files = append(files, mine...)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, context.Cause(ctx)
+	}
+
+	total := NewReport()
+	for _, p := range partials {
+		total.Merge(p)
+	}
+	slices.SortFunc(files, func(a, b FileReport) int { return cmp.Compare(a.Path, b.Path) })
+	return &TreeReport{total, files}, nil
+}
+
+// CountTreeSequential does what CountTree does in one goroutine, one file
+// after another. It is the reference CountTree must agree with.
+func CountTreeSequential(ctx context.Context, root string, a *Analyzer, opts CountOptions) (*TreeReport, error) {
+	tr := &TreeReport{Total: NewReport()}
+	err := walkFiles(root, func(path string) error {
+		rep, err := countFile(ctx, path, a)
+		if err != nil {
+			return err
+		}
+		tr.Total.Merge(rep)
+		tr.Files = append(tr.Files, FileReport{path, rep.Tokens, Top(rep.Words, opts.TopN)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tr, nil
+}
+
+// walkFiles calls f for each regular file under root, in lexical order.
+func walkFiles(root string, f func(path string) error) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return f(path)
+	})
+}
+
+func countFile(ctx context.Context, path string, a *Analyzer) (*Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	rep, err := a.Analyze(ctxReader{ctx, f})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rep, nil
+}
+
+// ctxReader stops reading once its context is cancelled, so a cancelled count
+// does not have to finish the large file it is in the middle of.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r ctxReader) Read(p []byte) (int, error) {
+	if r.ctx.Err() != nil {
+		return 0, context.Cause(r.ctx)
+	}
+	return r.r.Read(p)
+}
+
+// Equal reports whether two tree reports hold the same counts.
+func (tr *TreeReport) Equal(o *TreeReport) bool {
+	if tr.Total.Tokens != o.Total.Tokens || !maps.Equal(tr.Total.Words, o.Total.Words) {
+		return false
+	}
+	if !maps.EqualFunc(tr.Total.NGrams, o.Total.NGrams, maps.Equal) {
+		return false
+	}
+	return slices.EqualFunc(tr.Files, o.Files, func(a, b FileReport) bool {
+		return a.Path == b.Path && a.Tokens == b.Tokens && slices.Equal(a.Top, b.Top)
+	})
+}
+
+// WriteFiles writes one line per file with its word count and top words.
+func (tr *TreeReport) WriteFiles(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "file\twords\ttop\n")
+	for _, f := range tr.Files {
+		fmt.Fprintf(tw, "%s\t%d\t%v\n", f.Path, f.Tokens, f.Top)
+	}
+	return tw.Flush()
+}
+
+// countCommand implements:
+//
+//	go run 9.map/*.go count [-workers N] [-files] [-verify] [analyze flags] dir
+//
+// Interrupting it with Ctrl-C cancels the count.
+func countCommand(args []string) error {
+	fs := flag.NewFlagSet("count", flag.ContinueOnError)
+	flags := addAnalyzeFlags(fs)
+	workers := fs.Int("workers", runtime.NumCPU(), "number of files counted at once")
+	perFile := fs.Bool("files", false, "also list every file with its top words")
+	verify := fs.Bool("verify", false, "count again sequentially and check the results agree")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: count [flags] dir")
+	}
+	if *flags.top < 0 {
+		return errors.New("-top must not be negative")
+	}
+	a, err := flags.analyzer()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	opts := CountOptions{Workers: *workers, TopN: 5}
+	tr, err := CountTree(ctx, fs.Arg(0), a, opts)
+	if err != nil {
+		return err
+	}
+	if *verify {
+		seq, err := CountTreeSequential(ctx, fs.Arg(0), a, opts)
+		if err != nil {
+			return err
+		}
+		if !tr.Equal(seq) {
+			return errors.New("parallel and sequential counts differ")
+		}
+		fmt.Fprintln(os.Stderr, "verified: parallel and sequential counts agree")
+	}
+	if *perFile {
+		if err := tr.WriteFiles(os.Stdout); err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+	return tr.Total.Write(os.Stdout, *flags.format, *flags.top)
+}
+
+func treeMain() {
+	dir, err := os.MkdirTemp("", "wordcount")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "logs"), 0o755)
+	texts := map[string]string{
+		"a.txt":      "I Love You! I Love You! I Love You!",
+		"b.txt":      "I Love Go!",
+		"logs/1.log": "disk full. disk full. retry",
+		"logs/2.log": "retry ok",
+	}
+	for name, text := range texts {
+		os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644)
+	}
+
+	a := &Analyzer{Fold: true, NGrams: []int{2}}
+	ctx := context.Background()
+	par, err := CountTree(ctx, dir, a, CountOptions{Workers: 3, TopN: 1})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	seq, _ := CountTreeSequential(ctx, dir, a, CountOptions{TopN: 1})
+	fmt.Println(par.Total.Tokens, Top(par.Total.Words, 3), par.Equal(seq)) // 19 [{i 4} {love 4} {you 3}] true
+	for _, f := range par.Files {
+		rel, _ := filepath.Rel(dir, f.Path)
+		fmt.Print(rel, f.Top, " ") // a.txt[{i 3}] b.txt[{go 1}] logs/1.log[{disk 2}] logs/2.log[{ok 1}]
+	}
+	fmt.Println()
+
+	// A cancelled context stops the count.
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = CountTree(cancelled, dir, a, CountOptions{})
+	fmt.Println(err) // context canceled
+}
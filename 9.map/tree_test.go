@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTree fills a temporary directory with n files of random words, spread
+// over a few nested directories, and returns its path.
+func writeTree(t *testing.T, n int) string {
+	t.Helper()
+	root := t.TempDir()
+	r := rand.New(rand.NewPCG(1, 2))
+	words := strings.Fields("I Love You Go love you go the a an of to in")
+	for i := range n {
+		dir := filepath.Join(root, fmt.Sprintf("d%d", i%4), fmt.Sprintf("e%d", i%3))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		var b strings.Builder
+		for range r.IntN(200) {
+			b.WriteString(words[r.IntN(len(words))])
+			b.WriteString(" ")
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%03d.txt", i)), []byte(b.String()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestCountTreeMatchesSequential(t *testing.T) {
+	root := writeTree(t, 120)
+	a := &Analyzer{Fold: true, NGrams: []int{2, 3}}
+	ctx := context.Background()
+	want, err := CountTreeSequential(ctx, root, a, CountOptions{TopN: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want.Files) != 120 {
+		t.Fatalf("CountTreeSequential found %d files, want 120", len(want.Files))
+	}
+	for _, workers := range []int{0, 1, 2, 3, 8, 200} {
+		got, err := CountTree(ctx, root, a, CountOptions{Workers: workers, TopN: 3})
+		if err != nil {
+			t.Fatalf("workers=%d: %v", workers, err)
+		}
+		if !got.Equal(want) {
+			t.Errorf("workers=%d: CountTree differs from CountTreeSequential: %d tokens, want %d",
+				workers, got.Total.Tokens, want.Total.Tokens)
+		}
+	}
+}
+
+func TestCountTreeEmpty(t *testing.T) {
+	got, err := CountTree(context.Background(), t.TempDir(), &Analyzer{}, CountOptions{Workers: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Total.Tokens != 0 || len(got.Files) != 0 {
+		t.Errorf("empty tree: %d tokens in %d files, want none", got.Total.Tokens, len(got.Files))
+	}
+}
+
+func TestCountTreeCancel(t *testing.T) {
+	root := writeTree(t, 50)
+	a := &Analyzer{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, workers := range []int{1, 4} {
+		if _, err := CountTree(ctx, root, a, CountOptions{Workers: workers}); !errors.Is(err, context.Canceled) {
+			t.Errorf("workers=%d: CountTree(cancelled) error = %v, want context.Canceled", workers, err)
+		}
+	}
+	if _, err := CountTreeSequential(ctx, root, a, CountOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("CountTreeSequential(cancelled) error = %v, want context.Canceled", err)
+	}
+
+	// The cause of the cancellation is what CountTree returns.
+	errStop := errors.New("stop")
+	ctx, cancelCause := context.WithCancelCause(context.Background())
+	cancelCause(errStop)
+	if _, err := CountTree(ctx, root, a, CountOptions{Workers: 4}); !errors.Is(err, errStop) {
+		t.Errorf("CountTree error = %v, want the cause %v", err, errStop)
+	}
+}
+
+func TestCountTreeMissingRoot(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "missing")
+	if _, err := CountTree(context.Background(), root, &Analyzer{}, CountOptions{Workers: 2}); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("CountTree(missing root) error = %v, want os.ErrNotExist", err)
+	}
+}